@@ -2,7 +2,9 @@ package ast
 
 import (
 	"bytes"
+	"fmt"
 	"lemon/token"
+	"math/big"
 	"strings"
 )
 
@@ -10,6 +12,7 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // position of the node's token in the source
 }
 
 // All statement nodes implement this
@@ -36,6 +39,13 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
@@ -47,14 +57,17 @@ func (p *Program) String() string {
 }
 
 // Statements
+// LetStatement binds Value to Name, which is an identifier or a pattern
+// that destructures the value.
 type LetStatement struct {
 	Token token.Token // token.LET token
-	Name  *Identifier
+	Name  Pattern
 	Value Expression
 }
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos }
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
@@ -78,6 +91,7 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos }
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -99,6 +113,7 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position  { return es.Token.Pos }
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -106,6 +121,170 @@ func (es *ExpressionStatement) String() string {
 	return ""
 }
 
+type WhileStatement struct {
+	Token     token.Token // 'while' token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position  { return ws.Token.Pos }
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while (")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(") { ")
+	out.WriteString(ws.Body.String())
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// ForStatement is a C-style loop. Init, Condition and Post are optional.
+type ForStatement struct {
+	Token     token.Token // 'for' token
+	Init      Statement
+	Condition Expression
+	Post      Statement
+	Body      *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	if fs.Init != nil {
+		out.WriteString(strings.TrimSuffix(fs.Init.String(), ";"))
+	}
+	out.WriteString("; ")
+	if fs.Condition != nil {
+		out.WriteString(fs.Condition.String())
+	}
+	out.WriteString("; ")
+	if fs.Post != nil {
+		out.WriteString(strings.TrimSuffix(fs.Post.String(), ";"))
+	}
+	out.WriteString(") { ")
+	out.WriteString(fs.Body.String())
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// ForInStatement loops over the elements of an array, the characters of a
+// string or the keys of a map. Key is only set in the two variable form,
+// `for (k, v in x)`, where it holds the index or the map key.
+type ForInStatement struct {
+	Token    token.Token // 'for' token
+	Key      *Identifier
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForInStatement) statementNode()       {}
+func (fs *ForInStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForInStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *ForInStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	if fs.Key != nil {
+		out.WriteString(fs.Key.String() + ", ")
+	}
+	out.WriteString(fs.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") { ")
+	out.WriteString(fs.Body.String())
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// SwitchStatement runs the first case with a value equal to Subject, or
+// the default case if none is. A case ending in fallthrough continues with
+// the next one.
+type SwitchStatement struct {
+	Token   token.Token // 'switch' token
+	Subject Expression
+	Cases   []*SwitchCase
+}
+
+func (ss *SwitchStatement) statementNode()       {}
+func (ss *SwitchStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *SwitchStatement) Pos() token.Position  { return ss.Token.Pos }
+func (ss *SwitchStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("switch (")
+	out.WriteString(ss.Subject.String())
+	out.WriteString(") {")
+	for _, c := range ss.Cases {
+		out.WriteString(" " + c.String())
+	}
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// SwitchCase is a `case a, b:` clause, or the `default:` clause when
+// Values is empty.
+type SwitchCase struct {
+	Token       token.Token // 'case' or 'default' token
+	Values      []Expression
+	Body        *BlockStatement
+	Fallthrough bool
+}
+
+func (sc *SwitchCase) TokenLiteral() string { return sc.Token.Literal }
+func (sc *SwitchCase) Pos() token.Position  { return sc.Token.Pos }
+func (sc *SwitchCase) String() string {
+	var out bytes.Buffer
+
+	if len(sc.Values) == 0 {
+		out.WriteString("default:")
+	} else {
+		values := []string{}
+		for _, v := range sc.Values {
+			values = append(values, v.String())
+		}
+		out.WriteString("case " + strings.Join(values, ", ") + ":")
+	}
+
+	if body := sc.Body.String(); body != "" {
+		out.WriteString(" " + body)
+	}
+	if sc.Fallthrough {
+		out.WriteString(" fallthrough;")
+	}
+
+	return out.String()
+}
+
+type BreakStatement struct {
+	Token token.Token // 'break' token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) String() string       { return bs.TokenLiteral() + ";" }
+
+type ContinueStatement struct {
+	Token token.Token // 'continue' token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() token.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) String() string       { return cs.TokenLiteral() + ";" }
+
 type BlockStatement struct {
 	Token      token.Token // { token
 	Statements []Statement
@@ -113,6 +292,7 @@ type BlockStatement struct {
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -131,6 +311,7 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
 func (i *Identifier) String() string       { return i.Value }
 
 type Boolean struct {
@@ -140,6 +321,7 @@ type Boolean struct {
 
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
 type IntegerLiteral struct {
@@ -149,8 +331,30 @@ type IntegerLiteral struct {
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// BigIntegerLiteral is an integer literal too large for an int64.
+type BigIntegerLiteral struct {
+	Token token.Token
+	Value *big.Int
+}
+
+func (bl *BigIntegerLiteral) expressionNode()      {}
+func (bl *BigIntegerLiteral) TokenLiteral() string { return bl.Token.Literal }
+func (bl *BigIntegerLiteral) Pos() token.Position  { return bl.Token.Pos }
+func (bl *BigIntegerLiteral) String() string       { return bl.Token.Literal }
+
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 type PrefixExpression struct {
 	Token    token.Token // prefix token
 	Operator string
@@ -159,6 +363,7 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -179,6 +384,7 @@ type InfixExpression struct {
 
 func (oe *InfixExpression) expressionNode()      {}
 func (oe *InfixExpression) TokenLiteral() string { return oe.Token.Literal }
+func (oe *InfixExpression) Pos() token.Position  { return oe.Token.Pos }
 func (oe *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -191,26 +397,60 @@ func (oe *InfixExpression) String() string {
 	return out.String()
 }
 
+// AssignExpression stores Value into Target, an identifier or an index
+// expression. Compound operators like "+=" combine the current value of
+// Target with Value first.
+type AssignExpression struct {
+	Token    token.Token // the assignment operator
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position  { return ae.Token.Pos }
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// IfExpression is an if with an optional else branch. An `else if` chain
+// is kept as ElseIf, in which case Alternative is nil.
 type IfExpression struct {
 	Token       token.Token // 'if' token
 	Condition   Expression
 	Consequence *BlockStatement
+	ElseIf      *IfExpression
 	Alternative *BlockStatement
 }
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos }
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("if")
+	out.WriteString("if (")
 	out.WriteString(ie.Condition.String())
-	out.WriteString(" ")
+	out.WriteString(") { ")
 	out.WriteString(ie.Consequence.String())
+	out.WriteString(" }")
 
-	if ie.Alternative != nil {
-		out.WriteString("else ")
+	if ie.ElseIf != nil {
+		out.WriteString(" else ")
+		out.WriteString(ie.ElseIf.String())
+	} else if ie.Alternative != nil {
+		out.WriteString(" else { ")
 		out.WriteString(ie.Alternative.String())
+		out.WriteString(" }")
 	}
 
 	return out.String()
@@ -218,12 +458,13 @@ func (ie *IfExpression) String() string {
 
 type FunctionLiteral struct {
 	Token      token.Token // 'fn' token
-	Parameters []*Identifier
+	Parameters []Pattern
 	Body       *BlockStatement
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
@@ -241,14 +482,19 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// CallExpression is `function(arguments)`, or `function?.(arguments)`
+// when Optional, which is null without evaluating the arguments when the
+// function is null.
 type CallExpression struct {
 	Token     token.Token // '(' token
 	Function  Expression  // Identifier or FunctionLiteral
 	Arguments []Expression
+	Optional  bool
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return ce.Token.Pos }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -258,6 +504,9 @@ func (ce *CallExpression) String() string {
 	}
 
 	out.WriteString(ce.Function.String())
+	if ce.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
@@ -265,6 +514,35 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// PipeExpression is `x |> f(a)`, which calls f(x, a). If Right isn't a
+// call, it evaluates to a function that is called with x alone.
+type PipeExpression struct {
+	Token token.Token // the '|>' token
+	Left  Expression
+	Right Expression
+}
+
+func (pe *PipeExpression) expressionNode()      {}
+func (pe *PipeExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PipeExpression) Pos() token.Position  { return pe.Token.Pos }
+func (pe *PipeExpression) String() string {
+	return "(" + pe.Left.String() + " |> " + pe.Right.String() + ")"
+}
+
+// KeywordArgument is a call argument passed by parameter name, `f(y: 2)`.
+type KeywordArgument struct {
+	Token token.Token // the name token
+	Name  *Identifier
+	Value Expression
+}
+
+func (ka *KeywordArgument) expressionNode()      {}
+func (ka *KeywordArgument) TokenLiteral() string { return ka.Token.Literal }
+func (ka *KeywordArgument) Pos() token.Position  { return ka.Token.Pos }
+func (ka *KeywordArgument) String() string {
+	return ka.Name.String() + ": " + ka.Value.String()
+}
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -272,7 +550,74 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
-func (sl *StringLiteral) String() string       { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
+func (sl *StringLiteral) String() string       { return quoteString(sl.Value) }
+
+// InterpolatedString is a string literal with embedded ${expressions}.
+// Text segments are kept in Parts as *StringLiteral nodes.
+type InterpolatedString struct {
+	Token token.Token // the first token.INTERPOLATION token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) Pos() token.Position  { return is.Token.Pos }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("\"")
+	for _, part := range is.Parts {
+		if text, ok := part.(*StringLiteral); ok {
+			out.WriteString(escapeString(text.Value))
+		} else {
+			out.WriteString("${")
+			out.WriteString(part.String())
+			out.WriteString("}")
+		}
+	}
+	out.WriteString("\"")
+
+	return out.String()
+}
+
+// quoteString renders s as a double-quoted Lemon string literal that the
+// lexer reads back as s.
+func quoteString(s string) string {
+	return "\"" + escapeString(s) + "\""
+}
+
+func escapeString(s string) string {
+	var out strings.Builder
+
+	for i, ch := range s {
+		switch ch {
+		case '"', '\\':
+			out.WriteByte('\\')
+			out.WriteRune(ch)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '$':
+			// keep "${" from starting an interpolation
+			if strings.HasPrefix(s[i:], "${") {
+				out.WriteByte('\\')
+			}
+			out.WriteRune(ch)
+		default:
+			if ch < ' ' || ch == 0x7f {
+				fmt.Fprintf(&out, `\u{%x}`, ch)
+			} else {
+				out.WriteRune(ch)
+			}
+		}
+	}
+
+	return out.String()
+}
 
 type ArrayLiteral struct {
 	Token    token.Token
@@ -281,6 +626,7 @@ type ArrayLiteral struct {
 
 func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos }
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -296,19 +642,26 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// IndexExpression is `left[index]`, or `left?.[index]` when Optional,
+// which is null rather than an error when left is null.
 type IndexExpression struct {
-	Token token.Token
-	Left  Expression
-	Index Expression
+	Token    token.Token
+	Left     Expression
+	Index    Expression
+	Optional bool
 }
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Token.Pos }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
@@ -316,19 +669,102 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// MemberExpression is `object.property`, short for `object["property"]`
+// on maps. Called, `object.property(args)` is a method call. When Optional,
+// `object?.property` is null rather than an error when object is null.
+type MemberExpression struct {
+	Token    token.Token // the '.' or '?.' token
+	Object   Expression
+	Property *Identifier
+	Optional bool
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) Pos() token.Position  { return me.Token.Pos }
+func (me *MemberExpression) String() string {
+	op := "."
+	if me.Optional {
+		op = "?."
+	}
+	return "(" + me.Object.String() + op + me.Property.String() + ")"
+}
+
+// SliceExpression is `left[start:end]`. Start and End are nil when left
+// out. Like an index, it can be Optional.
+type SliceExpression struct {
+	Token    token.Token // The [ token
+	Left     Expression
+	Start    Expression
+	End      Expression
+	Optional bool
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) Pos() token.Position  { return se.Token.Pos }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	if se.Optional {
+		out.WriteString("?.")
+	}
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// RangeExpression is `start..end`, or `start..=end` to include end.
+type RangeExpression struct {
+	Token     token.Token // the '..' or '..=' token
+	Start     Expression
+	End       Expression
+	Inclusive bool
+}
+
+func (re *RangeExpression) expressionNode()      {}
+func (re *RangeExpression) TokenLiteral() string { return re.Token.Literal }
+func (re *RangeExpression) Pos() token.Position  { return re.Token.Pos }
+func (re *RangeExpression) String() string {
+	op := ".."
+	if re.Inclusive {
+		op = "..="
+	}
+	return "(" + re.Start.String() + op + re.End.String() + ")"
+}
+
+// MapLiteral is `{key: value, ...spread}`. Keys holds the keys of Pairs
+// and the spreads in source order, since later entries override earlier
+// ones.
 type MapLiteral struct {
 	Token token.Token
 	Pairs map[Expression]Expression
+	Keys  []Expression
 }
 
 func (ml *MapLiteral) expressionNode()      {}
 func (ml *MapLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MapLiteral) Pos() token.Position  { return ml.Token.Pos }
 func (ml *MapLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range ml.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range ml.OrderedKeys() {
+		if spread, ok := key.(*SpreadElement); ok {
+			pairs = append(pairs, spread.String())
+			continue
+		}
+		pairs = append(pairs, key.String()+": "+ml.Pairs[key].String())
 	}
 
 	out.WriteString("{")
@@ -338,6 +774,92 @@ func (ml *MapLiteral) String() string {
 	return out.String()
 }
 
+// OrderedKeys returns Keys, or the keys of Pairs in no particular order
+// for a literal built without Keys.
+func (ml *MapLiteral) OrderedKeys() []Expression {
+	if ml.Keys != nil {
+		return ml.Keys
+	}
+
+	keys := []Expression{}
+	for key := range ml.Pairs {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// ListComprehension is `[element for x in xs if condition]`.
+type ListComprehension struct {
+	Token   token.Token // the '[' token
+	Element Expression
+	Clause  *ComprehensionClause
+}
+
+func (lc *ListComprehension) expressionNode()      {}
+func (lc *ListComprehension) TokenLiteral() string { return lc.Token.Literal }
+func (lc *ListComprehension) Pos() token.Position  { return lc.Token.Pos }
+func (lc *ListComprehension) String() string {
+	return "[" + lc.Element.String() + " " + lc.Clause.String() + "]"
+}
+
+// MapComprehension is `{key: value for k, v in m if condition}`.
+type MapComprehension struct {
+	Token  token.Token // the '{' token
+	Key    Expression
+	Value  Expression
+	Clause *ComprehensionClause
+}
+
+func (mc *MapComprehension) expressionNode()      {}
+func (mc *MapComprehension) TokenLiteral() string { return mc.Token.Literal }
+func (mc *MapComprehension) Pos() token.Position  { return mc.Token.Pos }
+func (mc *MapComprehension) String() string {
+	return "{" + mc.Key.String() + ": " + mc.Value.String() + " " + mc.Clause.String() + "}"
+}
+
+// ComprehensionClause is the `for k, v in iterable if condition` part of a
+// comprehension. Key and Condition are optional, and the variables get the
+// same values as in a for-in loop.
+type ComprehensionClause struct {
+	Token     token.Token // 'for' token
+	Key       *Identifier
+	Value     *Identifier
+	Iterable  Expression
+	Condition Expression
+}
+
+func (cc *ComprehensionClause) TokenLiteral() string { return cc.Token.Literal }
+func (cc *ComprehensionClause) Pos() token.Position  { return cc.Token.Pos }
+func (cc *ComprehensionClause) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for ")
+	if cc.Key != nil {
+		out.WriteString(cc.Key.String() + ", ")
+	}
+	out.WriteString(cc.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(cc.Iterable.String())
+	if cc.Condition != nil {
+		out.WriteString(" if ")
+		out.WriteString(cc.Condition.String())
+	}
+
+	return out.String()
+}
+
+// SpreadElement is `...value` in an array literal, map literal or call
+// arguments, which inserts the elements or pairs of value in its place.
+type SpreadElement struct {
+	Token token.Token // the '...' token
+	Value Expression
+}
+
+func (se *SpreadElement) expressionNode()      {}
+func (se *SpreadElement) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadElement) Pos() token.Position  { return se.Token.Pos }
+func (se *SpreadElement) String() string       { return "..." + se.Value.String() }
+
 type MacroLiteral struct {
 	Token      token.Token // 'macro' token
 	Parameters []*Identifier
@@ -346,6 +868,7 @@ type MacroLiteral struct {
 
 func (ml *MacroLiteral) expressionNode()      {}
 func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) Pos() token.Position  { return ml.Token.Pos }
 func (ml *MacroLiteral) String() string {
 	var out bytes.Buffer
 
@@ -26,3 +26,24 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestStringLiteralString(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"hello", `"hello"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"a\nb\tc", `"a\nb\tc"`},
+		{`back\slash`, `"back\\slash"`},
+		{"bell\x07", `"bell\u{7}"`},
+		{"日本語", `"日本語"`},
+	}
+
+	for _, tt := range tests {
+		lit := &StringLiteral{Value: tt.value}
+		if lit.String() != tt.expected {
+			t.Errorf("lit.String() wrong. expected=%q, got=%q", tt.expected, lit.String())
+		}
+	}
+}
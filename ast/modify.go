@@ -14,6 +14,29 @@ func Modify(node Node, modifier ModifierFunc) Node {
 		node.Left, _ = Modify(node.Left, modifier).(Expression)
 		node.Right, _ = Modify(node.Right, modifier).(Expression)
 
+	case *AssignExpression:
+		node.Target, _ = Modify(node.Target, modifier).(Expression)
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+
+	case *CallExpression:
+		node.Function, _ = Modify(node.Function, modifier).(Expression)
+		for i, arg := range node.Arguments {
+			node.Arguments[i], _ = Modify(arg, modifier).(Expression)
+		}
+
+	case *PipeExpression:
+		node.Left, _ = Modify(node.Left, modifier).(Expression)
+		// a call on the right is completed by the pipe, so the modifier
+		// sees the pipe rather than the call
+		if call, ok := node.Right.(*CallExpression); ok {
+			call.Function, _ = Modify(call.Function, modifier).(Expression)
+			for i, arg := range call.Arguments {
+				call.Arguments[i], _ = Modify(arg, modifier).(Expression)
+			}
+		} else {
+			node.Right, _ = Modify(node.Right, modifier).(Expression)
+		}
+
 	case *PrefixExpression:
 		node.Right, _ = Modify(node.Right, modifier).(Expression)
 
@@ -21,9 +44,28 @@ func Modify(node Node, modifier ModifierFunc) Node {
 		node.Left, _ = Modify(node.Left, modifier).(Expression)
 		node.Index, _ = Modify(node.Index, modifier).(Expression)
 
+	case *MemberExpression:
+		node.Object, _ = Modify(node.Object, modifier).(Expression)
+
+	case *SliceExpression:
+		node.Left, _ = Modify(node.Left, modifier).(Expression)
+		if node.Start != nil {
+			node.Start, _ = Modify(node.Start, modifier).(Expression)
+		}
+		if node.End != nil {
+			node.End, _ = Modify(node.End, modifier).(Expression)
+		}
+
+	case *RangeExpression:
+		node.Start, _ = Modify(node.Start, modifier).(Expression)
+		node.End, _ = Modify(node.End, modifier).(Expression)
+
 	case *IfExpression:
 		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
 		node.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
+		if node.ElseIf != nil {
+			node.ElseIf, _ = Modify(node.ElseIf, modifier).(*IfExpression)
+		}
 		if node.Alternative != nil {
 			node.Alternative, _ = Modify(node.Alternative, modifier).(*BlockStatement)
 		}
@@ -32,18 +74,78 @@ func Modify(node Node, modifier ModifierFunc) Node {
 			node.Statements[i], _ = Modify(statement, modifier).(Statement)
 		}
 
+	case *WhileStatement:
+		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+	case *ForStatement:
+		if node.Init != nil {
+			node.Init, _ = Modify(node.Init, modifier).(Statement)
+		}
+		if node.Condition != nil {
+			node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		}
+		if node.Post != nil {
+			node.Post, _ = Modify(node.Post, modifier).(Statement)
+		}
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+	case *ForInStatement:
+		node.Iterable, _ = Modify(node.Iterable, modifier).(Expression)
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+
+	case *SwitchStatement:
+		node.Subject, _ = Modify(node.Subject, modifier).(Expression)
+		for _, c := range node.Cases {
+			for i, value := range c.Values {
+				c.Values[i], _ = Modify(value, modifier).(Expression)
+			}
+			c.Body, _ = Modify(c.Body, modifier).(*BlockStatement)
+		}
+
 	case *ReturnStatement:
 		node.ReturnValue, _ = Modify(node.ReturnValue, modifier).(Expression)
 	case *LetStatement:
+		node.Name, _ = Modify(node.Name, modifier).(Pattern)
 		node.Value, _ = Modify(node.Value, modifier).(Expression)
 
 	case *FunctionLiteral:
 		for i, param := range node.Parameters {
-			node.Parameters[i], _ = Modify(param, modifier).(*Identifier)
+			node.Parameters[i], _ = Modify(param, modifier).(Pattern)
 		}
 
 		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
 
+	case *MatchExpression:
+		node.Subject, _ = Modify(node.Subject, modifier).(Expression)
+		for _, arm := range node.Arms {
+			arm.Pattern, _ = Modify(arm.Pattern, modifier).(Pattern)
+			if arm.Guard != nil {
+				arm.Guard, _ = Modify(arm.Guard, modifier).(Expression)
+			}
+			arm.Body, _ = Modify(arm.Body, modifier).(Expression)
+		}
+	case *LiteralPattern:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+	case *ArrayPattern:
+		for i, element := range node.Elements {
+			node.Elements[i], _ = Modify(element, modifier).(Pattern)
+		}
+	case *DefaultPattern:
+		node.Pattern, _ = Modify(node.Pattern, modifier).(Pattern)
+		node.Default, _ = Modify(node.Default, modifier).(Expression)
+	case *MapPattern:
+		for i, key := range node.Keys {
+			node.Keys[i], _ = Modify(key, modifier).(Expression)
+			node.Values[i], _ = Modify(node.Values[i], modifier).(Pattern)
+		}
+
+	case *KeywordArgument:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+
+	case *InterpolatedString:
+		for i, part := range node.Parts {
+			node.Parts[i], _ = Modify(part, modifier).(Expression)
+		}
+
 	case *ArrayLiteral:
 		for i, elem := range node.Elements {
 			node.Elements[i], _ = Modify(elem, modifier).(Expression)
@@ -51,12 +153,32 @@ func Modify(node Node, modifier ModifierFunc) Node {
 
 	case *MapLiteral:
 		newPairs := make(map[Expression]Expression)
-		for key, value := range node.Pairs {
+		newKeys := []Expression{}
+		for _, key := range node.OrderedKeys() {
 			k, _ := Modify(key, modifier).(Expression)
-			v, _ := Modify(value, modifier).(Expression)
-			newPairs[k] = v
+			if _, ok := key.(*SpreadElement); !ok {
+				newPairs[k], _ = Modify(node.Pairs[key], modifier).(Expression)
+			}
+			newKeys = append(newKeys, k)
 		}
 		node.Pairs = newPairs
+		node.Keys = newKeys
+
+	case *ListComprehension:
+		node.Element, _ = Modify(node.Element, modifier).(Expression)
+		node.Clause, _ = Modify(node.Clause, modifier).(*ComprehensionClause)
+	case *MapComprehension:
+		node.Key, _ = Modify(node.Key, modifier).(Expression)
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+		node.Clause, _ = Modify(node.Clause, modifier).(*ComprehensionClause)
+	case *ComprehensionClause:
+		node.Iterable, _ = Modify(node.Iterable, modifier).(Expression)
+		if node.Condition != nil {
+			node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		}
+
+	case *SpreadElement:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
 	}
 
 	return modifier(node)
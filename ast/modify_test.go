@@ -51,6 +51,10 @@ func TestModify(t *testing.T) {
 			&InfixExpression{Left: two(), Operator: "+", Right: one()},
 			&InfixExpression{Left: two(), Operator: "+", Right: two()},
 		},
+		{
+			&AssignExpression{Target: &IndexExpression{Left: one(), Index: one()}, Operator: "=", Value: one()},
+			&AssignExpression{Target: &IndexExpression{Left: two(), Index: two()}, Operator: "=", Value: two()},
+		},
 		{
 			&PrefixExpression{Operator: "-", Right: one()},
 			&PrefixExpression{Operator: "-", Right: two()},
@@ -87,6 +91,150 @@ func TestModify(t *testing.T) {
 				},
 			},
 		},
+		{
+			&IfExpression{
+				Condition:   one(),
+				Consequence: &BlockStatement{Statements: []Statement{}},
+				ElseIf: &IfExpression{
+					Condition: one(),
+					Consequence: &BlockStatement{
+						Statements: []Statement{
+							&ExpressionStatement{Expression: one()},
+						},
+					},
+				},
+			},
+			&IfExpression{
+				Condition:   two(),
+				Consequence: &BlockStatement{Statements: []Statement{}},
+				ElseIf: &IfExpression{
+					Condition: two(),
+					Consequence: &BlockStatement{
+						Statements: []Statement{
+							&ExpressionStatement{Expression: two()},
+						},
+					},
+				},
+			},
+		},
+		{
+			&CallExpression{Function: one(), Arguments: []Expression{one(), one()}},
+			&CallExpression{Function: two(), Arguments: []Expression{two(), two()}},
+		},
+		{
+			&PipeExpression{Left: one(), Right: &CallExpression{Function: one(), Arguments: []Expression{one()}}},
+			&PipeExpression{Left: two(), Right: &CallExpression{Function: two(), Arguments: []Expression{two()}}},
+		},
+		{
+			&ArrayLiteral{Elements: []Expression{one(), &SpreadElement{Value: one()}}},
+			&ArrayLiteral{Elements: []Expression{two(), &SpreadElement{Value: two()}}},
+		},
+		{
+			&MemberExpression{Object: one(), Property: &Identifier{Value: "x"}},
+			&MemberExpression{Object: two(), Property: &Identifier{Value: "x"}},
+		},
+		{
+			&SliceExpression{Left: one(), Start: one(), End: one()},
+			&SliceExpression{Left: two(), Start: two(), End: two()},
+		},
+		{
+			&SliceExpression{Left: one(), End: one()},
+			&SliceExpression{Left: two(), End: two()},
+		},
+		{
+			&RangeExpression{Start: one(), End: one()},
+			&RangeExpression{Start: two(), End: two()},
+		},
+		{
+			&PipeExpression{Left: one(), Right: one()},
+			&PipeExpression{Left: two(), Right: two()},
+		},
+		{
+			&ListComprehension{
+				Element: one(),
+				Clause:  &ComprehensionClause{Value: &Identifier{Value: "x"}, Iterable: one(), Condition: one()},
+			},
+			&ListComprehension{
+				Element: two(),
+				Clause:  &ComprehensionClause{Value: &Identifier{Value: "x"}, Iterable: two(), Condition: two()},
+			},
+		},
+		{
+			&MapComprehension{
+				Key:    one(),
+				Value:  one(),
+				Clause: &ComprehensionClause{Value: &Identifier{Value: "x"}, Iterable: one()},
+			},
+			&MapComprehension{
+				Key:    two(),
+				Value:  two(),
+				Clause: &ComprehensionClause{Value: &Identifier{Value: "x"}, Iterable: two()},
+			},
+		},
+		{
+			&MatchExpression{
+				Subject: one(),
+				Arms: []*MatchArm{
+					{Pattern: &LiteralPattern{Value: one()}, Guard: one(), Body: one()},
+				},
+			},
+			&MatchExpression{
+				Subject: two(),
+				Arms: []*MatchArm{
+					{Pattern: &LiteralPattern{Value: two()}, Guard: two(), Body: two()},
+				},
+			},
+		},
+		{
+			&WhileStatement{
+				Condition: one(),
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&WhileStatement{
+				Condition: two(),
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&ForStatement{
+				Init:      &LetStatement{Value: one()},
+				Condition: one(),
+				Post:      &ExpressionStatement{Expression: one()},
+				Body:      &BlockStatement{Statements: []Statement{}},
+			},
+			&ForStatement{
+				Init:      &LetStatement{Value: two()},
+				Condition: two(),
+				Post:      &ExpressionStatement{Expression: two()},
+				Body:      &BlockStatement{Statements: []Statement{}},
+			},
+		},
+		{
+			&ForInStatement{
+				Iterable: one(),
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&ForInStatement{
+				Iterable: two(),
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
 		{
 			&ReturnStatement{ReturnValue: one()},
 			&ReturnStatement{ReturnValue: two()},
@@ -97,7 +245,7 @@ func TestModify(t *testing.T) {
 		},
 		{
 			&FunctionLiteral{
-				Parameters: []*Identifier{},
+				Parameters: []Pattern{},
 				Body: &BlockStatement{
 					Statements: []Statement{
 						&ExpressionStatement{Expression: one()},
@@ -105,7 +253,7 @@ func TestModify(t *testing.T) {
 				},
 			},
 			&FunctionLiteral{
-				Parameters: []*Identifier{},
+				Parameters: []Pattern{},
 				Body: &BlockStatement{
 					Statements: []Statement{
 						&ExpressionStatement{Expression: two()},
@@ -117,6 +265,10 @@ func TestModify(t *testing.T) {
 			&ArrayLiteral{Elements: []Expression{one(), one()}},
 			&ArrayLiteral{Elements: []Expression{two(), two()}},
 		},
+		{
+			&InterpolatedString{Parts: []Expression{&StringLiteral{Value: "a"}, one()}},
+			&InterpolatedString{Parts: []Expression{&StringLiteral{Value: "a"}, two()}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,175 @@
+package ast
+
+import (
+	"bytes"
+	"lemon/token"
+	"strings"
+)
+
+// Pattern is the left side of a match arm. A pattern either matches a
+// value, binding the names it contains, or it doesn't.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// An identifier pattern matches anything and binds it to the name.
+func (i *Identifier) patternNode() {}
+
+// WildcardPattern is `_`, which matches anything and binds nothing.
+type WildcardPattern struct {
+	Token token.Token // the '_' token
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) Pos() token.Position  { return wp.Token.Pos }
+func (wp *WildcardPattern) String() string       { return "_" }
+
+// LiteralPattern matches values equal to a number, string or boolean
+// literal, which may be negated.
+type LiteralPattern struct {
+	Token token.Token // the first token of the literal
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Token.Literal }
+func (lp *LiteralPattern) Pos() token.Position  { return lp.Token.Pos }
+func (lp *LiteralPattern) String() string       { return lp.Value.String() }
+
+// ArrayPattern matches arrays element by element. If the last element is
+// a RestPattern, longer arrays match too.
+type ArrayPattern struct {
+	Token    token.Token // the '[' token
+	Elements []Pattern
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) Pos() token.Position  { return ap.Token.Pos }
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// RestPattern is `...name`, binding the remaining elements as an array.
+// Name is nil for a bare `...`.
+type RestPattern struct {
+	Token token.Token // the '...' token
+	Name  *Identifier
+}
+
+func (rp *RestPattern) patternNode()         {}
+func (rp *RestPattern) TokenLiteral() string { return rp.Token.Literal }
+func (rp *RestPattern) Pos() token.Position  { return rp.Token.Pos }
+func (rp *RestPattern) String() string {
+	if rp.Name == nil {
+		return "..."
+	}
+	return "..." + rp.Name.String()
+}
+
+// DefaultPattern is a function parameter with a default value, `y = 10`,
+// which is evaluated when the call doesn't pass the argument.
+type DefaultPattern struct {
+	Token   token.Token // the '=' token
+	Pattern Pattern
+	Default Expression
+}
+
+func (dp *DefaultPattern) patternNode()         {}
+func (dp *DefaultPattern) TokenLiteral() string { return dp.Token.Literal }
+func (dp *DefaultPattern) Pos() token.Position  { return dp.Pattern.Pos() }
+func (dp *DefaultPattern) String() string {
+	return dp.Pattern.String() + " = " + dp.Default.String()
+}
+
+// MapPattern matches maps that have all of Keys, with each value matching
+// the pattern at the same index in Values. Other keys are ignored.
+type MapPattern struct {
+	Token  token.Token // the '{' token
+	Keys   []Expression
+	Values []Pattern
+}
+
+func (mp *MapPattern) patternNode()         {}
+func (mp *MapPattern) TokenLiteral() string { return mp.Token.Literal }
+func (mp *MapPattern) Pos() token.Position  { return mp.Token.Pos }
+func (mp *MapPattern) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for i, key := range mp.Keys {
+		pairs = append(pairs, key.String()+": "+mp.Values[i].String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// MatchArm is one `pattern if guard => body` case of a match expression.
+// Guard is optional.
+type MatchArm struct {
+	Token   token.Token // the first token of the pattern
+	Pattern Pattern
+	Guard   Expression
+	Body    Expression
+}
+
+func (ma *MatchArm) TokenLiteral() string { return ma.Token.Literal }
+func (ma *MatchArm) Pos() token.Position  { return ma.Token.Pos }
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
+}
+
+// MatchExpression evaluates the body of the first arm whose pattern
+// matches Subject and whose guard holds.
+type MatchExpression struct {
+	Token   token.Token // 'match' token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) Pos() token.Position  { return me.Token.Pos }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
@@ -13,18 +13,35 @@ func Exec(fileIn io.Reader, out io.Writer) {
 	var input string
 	scanner := bufio.NewScanner(fileIn)
 	for scanner.Scan() {
-		input += scanner.Text()
+		input += scanner.Text() + "\n"
 	}
 
-	l := lexer.New(input)
+	// positions in errors refer to the file name when we have one
+	filename := ""
+	if f, ok := fileIn.(interface{ Name() string }); ok {
+		filename = f.Name()
+	}
+
+	l := lexer.NewWithFilename(filename, input)
 	p := parser.New(l)
 	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(out, p.Errors())
+		return
+	}
+
 	env := object.NewEnvironment()
 
-	evaluator.Eval(program, env)
+	evaluated := evaluator.Eval(program, env)
+	if evaluated != nil && evaluated.Type() == object.ERROR_OBJ {
+		io.WriteString(out, evaluated.Inspect())
+		io.WriteString(out, "\n")
+	}
+}
 
-	// evaluated := evaluator.Eval(program, env)
-	// if evaluated != nil {
-	// 	io.WriteString(out, evaluated.Inspect())
-	// }
+func printParserErrors(out io.Writer, errors []string) {
+	io.WriteString(out, "parser errors:\n")
+	for _, msg := range errors {
+		io.WriteString(out, "\t"+msg+"\n")
+	}
 }
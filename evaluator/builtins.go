@@ -1,10 +1,14 @@
 package evaluator
 
 import (
+	"errors"
 	"fmt"
 	"lemon/object"
+	"math"
+	"math/big"
 	"sort"
 	"strconv"
+	"unicode/utf8"
 )
 
 var builtins = map[string]*object.Builtin{
@@ -21,7 +25,7 @@ var builtins = map[string]*object.Builtin{
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
@@ -36,9 +40,16 @@ var builtins = map[string]*object.Builtin{
 
 			switch arg := args[0].(type) {
 			case *object.Array:
+				if len(arg.Elements) == 0 {
+					return NULL
+				}
 				return arg.Elements[0]
 			case *object.String:
-				return &object.String{Value: string(arg.Value[0])}
+				if arg.Value == "" {
+					return NULL
+				}
+				r, _ := utf8.DecodeRuneInString(arg.Value)
+				return &object.String{Value: string(r)}
 			default:
 				return newError("argument to `first` not supported, got %s", args[0].Type())
 			}
@@ -54,10 +65,16 @@ var builtins = map[string]*object.Builtin{
 			switch arg := args[0].(type) {
 			case *object.Array:
 				length := len(arg.Elements)
+				if length == 0 {
+					return NULL
+				}
 				return arg.Elements[length-1]
 			case *object.String:
-				length := len(arg.Value)
-				return &object.String{Value: string(arg.Value[length-1])}
+				if arg.Value == "" {
+					return NULL
+				}
+				r, _ := utf8.DecodeLastRuneInString(arg.Value)
+				return &object.String{Value: string(r)}
 			default:
 				return newError("argument to `last` not supported, got %s", args[0].Type())
 			}
@@ -80,9 +97,9 @@ var builtins = map[string]*object.Builtin{
 				}
 				return NULL
 			case *object.String:
-				length := len(arg.Value)
-				if length > 0 {
-					return &object.String{Value: string(arg.Value[1:length])}
+				if arg.Value != "" {
+					_, size := utf8.DecodeRuneInString(arg.Value)
+					return &object.String{Value: arg.Value[size:]}
 				}
 				return NULL
 			default:
@@ -449,12 +466,26 @@ var builtins = map[string]*object.Builtin{
 			switch arg := args[0].(type) {
 			case *object.String:
 				value, err := strconv.ParseInt(arg.Value, 0, 64)
+				if errors.Is(err, strconv.ErrRange) {
+					if bigValue, ok := new(big.Int).SetString(arg.Value, 0); ok {
+						return normalizeBigInteger(bigValue)
+					}
+				}
 				if err != nil {
 					return newError("could not parse %q as integer", arg.Value)
 				}
 				return &object.Integer{Value: value}
-			case *object.Integer:
+			case *object.Integer, *object.BigInteger:
 				return arg
+			case *object.Float:
+				if math.IsNaN(arg.Value) || math.IsInf(arg.Value, 0) {
+					return newError("could not convert %s to integer", arg.Inspect())
+				}
+				if arg.Value < math.MinInt64 || arg.Value >= math.MaxInt64 {
+					value, _ := new(big.Float).SetFloat64(arg.Value).Int(nil)
+					return normalizeBigInteger(value)
+				}
+				return &object.Integer{Value: int64(arg.Value)}
 			case *object.Boolean:
 				if arg.Value {
 					return &object.Integer{Value: 1}
@@ -466,6 +497,35 @@ var builtins = map[string]*object.Builtin{
 			}
 		},
 	},
+	"float": {
+		Value: "float",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.String:
+				value, err := strconv.ParseFloat(arg.Value, 64)
+				if err != nil {
+					return newError("could not parse %q as float", arg.Value)
+				}
+				return &object.Float{Value: value}
+			case *object.Integer, *object.BigInteger:
+				return &object.Float{Value: toFloat(arg)}
+			case *object.Float:
+				return arg
+			case *object.Boolean:
+				if arg.Value {
+					return &object.Float{Value: 1}
+				} else {
+					return &object.Float{Value: 0}
+				}
+			default:
+				return newError("argument to `float` not supported, got %s", args[0].Type())
+			}
+		},
+	},
 	"str": {
 		Value: "str",
 		Fn: func(args ...object.Object) object.Object {
@@ -489,6 +549,16 @@ var builtins = map[string]*object.Builtin{
 					return FALSE
 				}
 				return TRUE
+			case *object.BigInteger:
+				if arg.Value.Sign() == 0 {
+					return FALSE
+				}
+				return TRUE
+			case *object.Float:
+				if arg.Value == 0 {
+					return FALSE
+				}
+				return TRUE
 			case *object.Boolean:
 				return arg
 			case *object.String:
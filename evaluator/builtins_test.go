@@ -14,17 +14,23 @@ func TestAllBuiltinFunctions(t *testing.T) {
 	}{
 		{`len([1, 2, 3])`, 3},
 		{`len("hello")`, 5},
+		{`len("héllo")`, 5},
+		{`len("日本語")`, 3},
 		{`first([1, 2, 3])`, 1},
 		{`first("hello")`, "h"},
+		{`first("日本語")`, "日"},
+		{`first("")`, nil},
 		{`last([1, 2, 3])`, 3},
 		{`last("hello")`, "o"},
+		{`last("日本語")`, "語"},
 		{`rest([1, 2, 3])`, []int{2, 3}},
 		{`rest("hello")`, "ello"},
+		{`rest("日本語")`, "本語"},
 		{`push([1, 2], 3)`, []int{1, 2, 3}},
 		{`pop([1, 2, 3])`, 3},
 		{`clone([1, 2, 3])`, []int{1, 2, 3}},
-		{`keys({"a": 1})`, []string{"a"}}, // order of keys is not guaranteed
-		{`values({"a": 1, "b": 2})`, []int{1, 2}},
+		{`keys({"a": 1})`, []string{"a"}},                 // order of keys is not guaranteed
+		{`sorted(values({"a": 2, "b": 1}))`, []int{1, 2}}, // order of values is not guaranteed
 		{`merge([1, 2], [3, 4])`, []int{1, 2, 3, 4}},
 		{`merge("hello", " world")`, "hello world"},
 		{`merge({"a": 1}, {"b": 2})`, map[string]int{"a": 1, "b": 2}},
@@ -38,6 +44,14 @@ func TestAllBuiltinFunctions(t *testing.T) {
 		{`print("hello")`, nil},
 		{`println("hello")`, nil},
 		{`int("123")`, 123},
+		{`int(3.99)`, 3},
+		{`int(-3.99)`, -3},
+		{`float("2.5")`, 2.5},
+		{`float(2)`, 2.0},
+		{`float(true)`, 1.0},
+		{`str(2.5)`, "2.5"},
+		{`str(2.0)`, "2.0"},
+		{`bool(0.0)`, false},
 		{`str(123)`, "123"},
 		{`bool(1)`, true},
 		{`bool(0)`, false},
@@ -48,6 +62,8 @@ func TestAllBuiltinFunctions(t *testing.T) {
 		switch expected := tt.expected.(type) {
 		case int:
 			testForIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testForFloatObject(t, evaluated, expected)
 		case string:
 			testForStringObject(t, evaluated, expected)
 		case []int:
@@ -86,6 +102,19 @@ func testForIntegerObject(t *testing.T, obj object.Object, expected int64) bool
 	return true
 }
 
+func testForFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+		return false
+	}
+	return true
+}
+
 func testForStringObject(t *testing.T, obj object.Object, expected string) bool {
 	result, ok := obj.(*object.String)
 	if !ok {
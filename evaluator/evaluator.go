@@ -1,18 +1,41 @@
 package evaluator
 
 import (
+	"bytes"
 	"fmt"
 	"lemon/ast"
 	"lemon/object"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"unicode/utf8"
 )
 
+// maxExponent bounds the exponent of ** for integer bases other than 0, 1
+// and -1, whose powers would otherwise be allowed to grow without limit.
+const maxExponent = math.MaxUint16
+
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL     = &object.Null{}
+	TRUE     = &object.Boolean{Value: true}
+	FALSE    = &object.Boolean{Value: false}
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+
+	// errors are stamped with the innermost node they surfaced from
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node, env)
@@ -33,7 +56,22 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if err := bindPattern(node.Name, val, env); err != nil {
+			return err
+		}
+
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+	case *ast.ForInStatement:
+		return evalForInStatement(node, env)
+	case *ast.SwitchStatement:
+		return evalSwitchStatement(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
 
 	case *ast.FunctionLiteral:
 		params := node.Parameters
@@ -44,30 +82,37 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalBlockStatement(node, env)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
 
 	// expressions
+	case *ast.PipeExpression:
+		return evalPipeExpression(node, env)
 	case *ast.CallExpression:
 		if node.Function.TokenLiteral() == "quote" {
 			return quote(node.Arguments[0], env)
 		}
 
-		fn := Eval(node.Function, env)
-		if isError(fn) {
-			return fn
-		}
-
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
+		return evalCallExpression(node, nil, env)
+	case *ast.KeywordArgument:
+		// only valid in argument lists, which take it apart; a macro could
+		// still put one elsewhere
+		return newError("keyword argument %s outside a call", node.Name.Value)
+	case *ast.SpreadElement:
+		// likewise taken apart by lists, maps and argument lists
+		return newError("cannot spread outside a list, map or argument list")
 
-		return callFunction(fn, args)
+	case *ast.MemberExpression:
+		return evalMemberExpression(node, env)
 
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
+		if node.Optional && left == NULL {
+			return NULL
+		}
 
 		index := Eval(node.Index, env)
 		if isError(index) {
@@ -75,6 +120,12 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return evalIndexExpression(left, index)
 
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
+
+	case *ast.RangeExpression:
+		return evalRangeExpression(node, env)
+
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
@@ -84,11 +135,21 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	case *ast.MapLiteral:
 		return evalMapLiteral(node, env)
+	case *ast.ListComprehension:
+		return evalListComprehension(node, env)
+	case *ast.MapComprehension:
+		return evalMapComprehension(node, env)
 
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.BigIntegerLiteral:
+		return &object.BigInteger{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
@@ -105,6 +166,10 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return left
 		}
 
+		if node.Operator == "&&" || node.Operator == "||" || node.Operator == "??" {
+			return evalLogicalExpression(node.Operator, left, node.Right, env)
+		}
+
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
@@ -112,6 +177,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalInfixExpression(node.Operator, left, right)
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
 
 	}
 
@@ -143,8 +210,8 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 		result = Eval(statement, env)
 
 		if result != nil {
-			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
 				return result
 			}
 		}
@@ -153,6 +220,193 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(ws.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		if result, stop := evalLoopBody(ws.Body, env); stop {
+			return result
+		}
+	}
+}
+
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	if fs.Init != nil {
+		if init := Eval(fs.Init, env); isError(init) {
+			return init
+		}
+	}
+
+	for {
+		if fs.Condition != nil {
+			condition := Eval(fs.Condition, env)
+			if isError(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return NULL
+			}
+		}
+
+		if result, stop := evalLoopBody(fs.Body, env); stop {
+			return result
+		}
+
+		if fs.Post != nil {
+			if post := Eval(fs.Post, env); isError(post) {
+				return post
+			}
+		}
+	}
+}
+
+func evalForInStatement(fs *ast.ForInStatement, env *object.Environment) object.Object {
+	iterable := Eval(fs.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	result := forEach(fs.Iterable, iterable, fs.Key == nil, func(key, value object.Object) (object.Object, bool) {
+		if fs.Key != nil {
+			env.Set(fs.Key.Value, key)
+		}
+		env.Set(fs.Value.Value, value)
+		return evalLoopBody(fs.Body, env)
+	})
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+// forEach calls each with the index or key and the value of every element
+// of iterable, the value of node, until it asks to stop, and returns what
+// it stopped with, or nil if it ran to the end. With keysOnly, maps pass
+// their keys as values.
+func forEach(node ast.Node, iterable object.Object, keysOnly bool, each func(key, value object.Object) (object.Object, bool)) object.Object {
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		for i, element := range iterable.Elements {
+			if result, stop := each(&object.Integer{Value: int64(i)}, element); stop {
+				return result
+			}
+		}
+	case *object.String:
+		for i, ch := range []rune(iterable.Value) {
+			char := &object.String{Value: string(ch)}
+			if result, stop := each(&object.Integer{Value: int64(i)}, char); stop {
+				return result
+			}
+		}
+	case *object.Range:
+		last, ok := rangeLast(iterable)
+		for i, n := iterable.Start, int64(0); ok; i, n = i+1, n+1 {
+			if result, stop := each(&object.Integer{Value: n}, &object.Integer{Value: i}); stop {
+				return result
+			}
+			if i == last {
+				break
+			}
+		}
+	case *object.Map:
+		for _, pair := range iterable.Pairs {
+			value := pair.Value
+			if keysOnly {
+				// a single variable gets the keys
+				value = pair.Key
+			}
+			if result, stop := each(pair.Key, value); stop {
+				return result
+			}
+		}
+	default:
+		return newErrorAt(node, "cannot iterate over %s", iterable.Type())
+	}
+
+	return nil
+}
+
+// evalSwitchStatement runs the body of the first case with a value equal
+// to the subject, or the default case if none is, then the bodies of any
+// cases it falls through to. A break leaves the switch.
+func evalSwitchStatement(ss *ast.SwitchStatement, env *object.Environment) object.Object {
+	subject := Eval(ss.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	start := -1
+	for i, c := range ss.Cases {
+		for _, value := range c.Values {
+			v := Eval(value, env)
+			if isError(v) {
+				return v
+			}
+			if evalInfixExpression("==", subject, v) == TRUE {
+				start = i
+				break
+			}
+		}
+		if start >= 0 {
+			break
+		}
+	}
+
+	if start < 0 {
+		for i, c := range ss.Cases {
+			if len(c.Values) == 0 {
+				start = i
+			}
+		}
+		if start < 0 {
+			return NULL
+		}
+	}
+
+	var result object.Object
+	for i := start; i < len(ss.Cases); i++ {
+		result = Eval(ss.Cases[i].Body, env)
+
+		switch result.(type) {
+		case *object.Break:
+			return NULL
+		case *object.ReturnValue, *object.Error, *object.Continue:
+			return result
+		}
+
+		if !ss.Cases[i].Fallthrough {
+			break
+		}
+	}
+
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+// evalLoopBody runs one iteration of a loop. It reports whether the loop
+// has to stop, along with what the loop evaluates to: NULL after a break,
+// or the return value or error that ended it.
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) (object.Object, bool) {
+	result := Eval(body, env)
+
+	switch result.(type) {
+	case *object.Break:
+		return NULL, true
+	case *object.ReturnValue, *object.Error:
+		return result, true
+	}
+
+	return nil, false
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return TRUE
@@ -185,12 +439,19 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 {
+			return normalizeBigInteger(new(big.Int).Neg(toBigInt(right)))
+		}
+		return &object.Integer{Value: -right.Value}
+	case *object.BigInteger:
+		return normalizeBigInteger(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalInfixExpression(
@@ -200,6 +461,11 @@ func evalInfixExpression(
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isInteger(left) && isInteger(right):
+		return evalBigIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		// at least one float: integers are widened
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 
@@ -225,21 +491,64 @@ func evalIntegerInfixExpression(
 
 	switch operator {
 
-	// arithmetic
+	// arithmetic, promoted to big integers on overflow
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		sum := leftVal + rightVal
+		if (leftVal^sum)&(rightVal^sum) < 0 {
+			return evalBigIntegerInfixExpression(operator, left, right)
+		}
+		return &object.Integer{Value: sum}
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		diff := leftVal - rightVal
+		if (leftVal^rightVal)&(leftVal^diff) < 0 {
+			return evalBigIntegerInfixExpression(operator, left, right)
+		}
+		return &object.Integer{Value: diff}
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		product := leftVal * rightVal
+		if leftVal != 0 && (product/leftVal != rightVal ||
+			leftVal == -1 && rightVal == math.MinInt64) {
+			return evalBigIntegerInfixExpression(operator, left, right)
+		}
+		return &object.Integer{Value: product}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			return evalBigIntegerInfixExpression(operator, left, right)
+		}
 		return &object.Integer{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("modulo by zero")
+		}
+		return &object.Integer{Value: leftVal % rightVal}
+	case "**", "<<":
+		return evalBigIntegerInfixExpression(operator, left, right)
+
+	// bitwise
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case ">>":
+		if rightVal < 0 {
+			return newError("negative shift count: %d", rightVal)
+		}
+		return &object.Integer{Value: leftVal >> rightVal}
 
 	// comparison
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -250,23 +559,245 @@ func evalIntegerInfixExpression(
 	}
 }
 
-func evalStringInfixExpression(
+func evalBigIntegerInfixExpression(
+	operator string,
+	left, right object.Object,
+) object.Object {
+	leftVal := toBigInt(left)
+	rightVal := toBigInt(right)
+
+	switch operator {
+
+	// arithmetic
+	case "+":
+		return normalizeBigInteger(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return normalizeBigInteger(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		return normalizeBigInteger(new(big.Int).Mul(leftVal, rightVal))
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newError("division by zero")
+		}
+		return normalizeBigInteger(new(big.Int).Quo(leftVal, rightVal))
+	case "%":
+		if rightVal.Sign() == 0 {
+			return newError("modulo by zero")
+		}
+		return normalizeBigInteger(new(big.Int).Rem(leftVal, rightVal))
+	case "**":
+		if rightVal.Sign() < 0 {
+			// negative powers aren't integers
+			return evalFloatInfixExpression(operator, left, right)
+		}
+		if leftVal.CmpAbs(big.NewInt(1)) > 0 &&
+			(!rightVal.IsUint64() || rightVal.Uint64() > maxExponent) {
+			return newError("exponent too large: %s", rightVal)
+		}
+		return normalizeBigInteger(new(big.Int).Exp(leftVal, rightVal, nil))
+
+	// bitwise
+	case "&":
+		return normalizeBigInteger(new(big.Int).And(leftVal, rightVal))
+	case "|":
+		return normalizeBigInteger(new(big.Int).Or(leftVal, rightVal))
+	case "^":
+		return normalizeBigInteger(new(big.Int).Xor(leftVal, rightVal))
+	case "<<", ">>":
+		if rightVal.Sign() < 0 {
+			return newError("negative shift count: %s", rightVal)
+		}
+		if !rightVal.IsUint64() || rightVal.Uint64() > math.MaxUint32 {
+			return newError("shift count too large: %s", rightVal)
+		}
+		if operator == "<<" {
+			return normalizeBigInteger(new(big.Int).Lsh(leftVal, uint(rightVal.Uint64())))
+		}
+		return normalizeBigInteger(new(big.Int).Rsh(leftVal, uint(rightVal.Uint64())))
+
+	// comparison
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalFloatInfixExpression(
 	operator string,
 	left, right object.Object,
 ) object.Object {
-	if operator != "+" {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+
+	// arithmetic
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("modulo by zero")
+		}
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		if leftVal == 0 && rightVal < 0 {
+			// a negative power of zero divides by zero
+			return newError("division by zero")
+		}
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
+
+	// comparison
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+
+	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
+}
+
+func isInteger(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.BigInteger:
+		return true
+	default:
+		return false
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.BigInteger, *object.Float:
+		return true
+	default:
+		return false
+	}
+}
+
+// toFloat widens a number to float64; callers check isNumber first.
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInteger:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
+	case *object.Float:
+		return obj.Value
+	default:
+		return 0
+	}
+}
 
+// toBigInt widens an integer to a big.Int; callers check isInteger first.
+// The result must not be modified, it may be shared with obj.
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInteger:
+		return obj.Value
+	default:
+		return new(big.Int)
+	}
+}
+
+// normalizeBigInteger turns results that fit in an int64 back into Integers.
+func normalizeBigInteger(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+	return &object.BigInteger{Value: value}
+}
+
+func evalStringInfixExpression(
+	operator string,
+	left, right object.Object,
+) object.Object {
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
 
-	// switch operator {
-	// case "+":
-	return &object.String{Value: leftVal + rightVal}
-	// default:
-	// 	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
-	// }
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalInterpolatedString(
+	node *ast.InterpolatedString,
+	env *object.Environment,
+) object.Object {
+	var out bytes.Buffer
+
+	for _, part := range node.Parts {
+		evaluated := Eval(part, env)
+		if isError(evaluated) {
+			return evaluated
+		}
+		out.WriteString(evaluated.Inspect())
+	}
+
+	return &object.String{Value: out.String()}
+}
+
+// evalLogicalExpression evaluates the right side of &&, || and ?? only
+// when the left side doesn't decide the result, and returns the deciding
+// operand itself: `x || default` is x when x is truthy, and `x ?? default`
+// is x unless it is null.
+func evalLogicalExpression(
+	operator string,
+	left object.Object,
+	right ast.Expression,
+	env *object.Environment,
+) object.Object {
+	switch operator {
+	case "??":
+		if left != NULL {
+			return left
+		}
+	default:
+		if isTruthy(left) == (operator == "||") {
+			return left
+		}
+	}
+
+	return Eval(right, env)
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
@@ -277,6 +808,8 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 
 	if isTruthy(condition) {
 		return Eval(ie.Consequence, env)
+	} else if ie.ElseIf != nil {
+		return Eval(ie.ElseIf, env)
 	} else if ie.Alternative != nil {
 		return Eval(ie.Alternative, env)
 	} else {
@@ -301,6 +834,14 @@ func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+// newErrorAt creates an error positioned at node, for errors about a part
+// of the node being evaluated that Eval would otherwise stamp on the whole.
+func newErrorAt(node ast.Node, format string, a ...interface{}) *object.Error {
+	err := newError(format, a...)
+	err.Pos = node.Pos()
+	return err
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
@@ -320,6 +861,121 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 	return newError("identifier not found: %s", node.Value)
 }
 
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		current, ok := env.Get(target.Value)
+		if !ok {
+			return newError("identifier not found: %s", target.Value)
+		}
+
+		val := evalAssignedValue(node, current, env)
+		if isError(val) {
+			return val
+		}
+
+		env.Assign(target.Value, val)
+		return val
+
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+
+		return evalIndexAssignExpression(node, left, index, env)
+
+	case *ast.MemberExpression:
+		left := Eval(target.Object, env)
+		if isError(left) {
+			return left
+		}
+		if left.Type() != object.MAP_OBJ {
+			return newError("field assignment not supported: %s", left.Type())
+		}
+
+		index := &object.String{Value: target.Property.Value}
+		return evalIndexAssignExpression(node, left, index, env)
+
+	default:
+		return newError("cannot assign to %s", node.Target)
+	}
+}
+
+// evalIndexAssignExpression assigns to left[index], where left and index
+// are already evaluated.
+func evalIndexAssignExpression(
+	node *ast.AssignExpression,
+	left, index object.Object,
+	env *object.Environment,
+) object.Object {
+	var current object.Object
+	if node.Operator != "=" {
+		current = evalIndexExpression(left, index)
+		if isError(current) {
+			return current
+		}
+	}
+
+	val := evalAssignedValue(node, current, env)
+	if isError(val) {
+		return val
+	}
+
+	return evalIndexAssignment(left, index, val)
+}
+
+// evalAssignedValue evaluates the right side of an assignment, combined
+// with the current value for compound operators.
+func evalAssignedValue(node *ast.AssignExpression, current object.Object, env *object.Environment) object.Object {
+	val := Eval(node.Value, env)
+	if isError(val) || node.Operator == "=" {
+		return val
+	}
+
+	operator := strings.TrimSuffix(node.Operator, "=")
+	return evalInfixExpression(operator, current, val)
+}
+
+func evalIndexAssignment(left, index, val object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		integer, ok := index.(*object.Integer)
+		if !ok {
+			return newError("array index must be INTEGER, got %s", index.Type())
+		}
+
+		idx := integer.Value
+		if idx < 0 {
+			idx += int64(len(left.Elements))
+		}
+
+		if idx < 0 || idx >= int64(len(left.Elements)) {
+			return newError("index out of range: %d", integer.Value)
+		}
+
+		left.Elements[idx] = val
+		return val
+
+	case *object.Map:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hashable key: %s", index.Type())
+		}
+
+		left.Pairs[key.HashKey()] = object.MapPair{Key: index, Value: val}
+		return val
+
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+}
+
 func evalExpressions(
 	exps []ast.Expression,
 	env *object.Environment,
@@ -327,6 +983,15 @@ func evalExpressions(
 	var result []object.Object
 
 	for _, e := range exps {
+		if spread, ok := e.(*ast.SpreadElement); ok {
+			elements := evalSpreadElement(spread, env)
+			if len(elements) == 1 && isError(elements[0]) {
+				return elements
+			}
+			result = append(result, elements...)
+			continue
+		}
+
 		evaluated := Eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
@@ -337,13 +1002,166 @@ func evalExpressions(
 	return result
 }
 
-func callFunction(fn object.Object, args []object.Object) object.Object {
+// evalSpreadElement returns the elements that `...value` stands for in a
+// list: the elements of an array or range, or the characters of a string.
+func evalSpreadElement(spread *ast.SpreadElement, env *object.Environment) []object.Object {
+	value := Eval(spread.Value, env)
+	if isError(value) {
+		return []object.Object{value}
+	}
+
+	switch value := value.(type) {
+	case *object.Array:
+		return value.Elements
+	case *object.String:
+		elements := []object.Object{}
+		for _, ch := range value.Value {
+			elements = append(elements, &object.String{Value: string(ch)})
+		}
+		return elements
+	case *object.Range:
+		elements := []object.Object{}
+		last, ok := rangeLast(value)
+		for i := value.Start; ok; i++ {
+			elements = append(elements, &object.Integer{Value: i})
+			if i == last {
+				break
+			}
+		}
+		return elements
+	default:
+		return []object.Object{newErrorAt(spread, "cannot spread %s into a list", value.Type())}
+	}
+}
+
+// evalPipeExpression calls the right side with the left value prepended
+// to its arguments, or as its only argument if the right side isn't a call.
+func evalPipeExpression(pe *ast.PipeExpression, env *object.Environment) object.Object {
+	left := Eval(pe.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	call, ok := pe.Right.(*ast.CallExpression)
+	if !ok {
+		fn := Eval(pe.Right, env)
+		if isError(fn) {
+			return fn
+		}
+		return callFunction(fn, []object.Object{left}, nil)
+	}
+
+	return evalCallExpression(call, []object.Object{left}, env)
+}
+
+// evalCallExpression calls the function of call with the piped values
+// followed by the call's own arguments. A method call passes the receiver
+// first when the method comes from the receiver's type.
+func evalCallExpression(
+	call *ast.CallExpression,
+	piped []object.Object,
+	env *object.Environment,
+) object.Object {
+	var fn, receiver object.Object
+
+	if member, ok := call.Function.(*ast.MemberExpression); ok {
+		obj := Eval(member.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		if member.Optional && obj == NULL {
+			return NULL
+		}
+
+		fn, receiver = lookupMethod(obj, member.Property.Value)
+		if fn == nil {
+			if call.Optional {
+				return NULL
+			}
+			return newError("unknown method %s for %s", member.Property.Value, obj.Type())
+		}
+	} else {
+		fn = Eval(call.Function, env)
+		if isError(fn) {
+			return fn
+		}
+	}
+
+	if call.Optional && fn == NULL {
+		return NULL
+	}
+
+	args, keywords := evalArguments(call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	args = append(piped, args...)
+	if receiver != nil {
+		args = append([]object.Object{receiver}, args...)
+	}
+
+	return callFunction(fn, args, keywords)
+}
+
+// evalArguments evaluates call arguments, returning keyword arguments by
+// name. Like evalExpressions, it returns just the error if one occurs.
+func evalArguments(
+	exps []ast.Expression,
+	env *object.Environment,
+) ([]object.Object, map[string]object.Object) {
+	var args []object.Object
+	var keywords map[string]object.Object
+
+	for _, e := range exps {
+		if spread, ok := e.(*ast.SpreadElement); ok {
+			elements := evalSpreadElement(spread, env)
+			if len(elements) == 1 && isError(elements[0]) {
+				return elements, nil
+			}
+			args = append(args, elements...)
+			continue
+		}
+
+		if kw, ok := e.(*ast.KeywordArgument); ok {
+			evaluated := Eval(kw.Value, env)
+			if isError(evaluated) {
+				return []object.Object{evaluated}, nil
+			}
+			if keywords == nil {
+				keywords = map[string]object.Object{}
+			}
+			keywords[kw.Name.Value] = evaluated
+			continue
+		}
+
+		evaluated := Eval(e, env)
+		if isError(evaluated) {
+			return []object.Object{evaluated}, nil
+		}
+		args = append(args, evaluated)
+	}
+
+	return args, keywords
+}
+
+func callFunction(
+	fn object.Object,
+	args []object.Object,
+	keywords map[string]object.Object,
+) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		extendedEnv, err := extendFunctionEnv(fn, args, keywords)
+		if err != nil {
+			return err
+		}
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *object.Builtin:
+		if len(keywords) > 0 {
+			return newError("builtin functions don't take keyword arguments")
+		}
 		return fn.Fn(args...)
 
 	default:
@@ -351,17 +1169,102 @@ func callFunction(fn object.Object, args []object.Object) object.Object {
 	}
 }
 
+// extendFunctionEnv binds the arguments of a call to the parameters of fn.
+// Each parameter takes its positional argument, else its keyword argument,
+// else its default, which is evaluated after the parameters before it are
+// bound. A rest parameter collects the remaining positional arguments.
 func extendFunctionEnv(
 	fn *object.Function,
 	args []object.Object,
-) *object.Environment {
+	keywords map[string]object.Object,
+) (*object.Environment, *object.Error) {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
-	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+	params := fn.Parameters
+	var rest *ast.RestPattern
+	if n := len(params); n > 0 {
+		if r, ok := params[n-1].(*ast.RestPattern); ok {
+			rest, params = r, params[:n-1]
+		}
+	}
+
+	if rest == nil && len(args) > len(params) {
+		if hasDefault(params) {
+			return nil, newError("wrong number of arguments. got=%d, want=at most %d", len(args), len(params))
+		}
+		return nil, newError("wrong number of arguments. got=%d, want=%d", len(args), len(params))
+	}
+
+	bound := map[string]bool{} // keyword arguments bound to a parameter
+	for paramIdx, param := range params {
+		pattern, def := param, ast.Expression(nil)
+		if dp, ok := param.(*ast.DefaultPattern); ok {
+			pattern, def = dp.Pattern, dp.Default
+		}
+
+		name := ""
+		if ident, ok := pattern.(*ast.Identifier); ok {
+			name = ident.Value
+		}
+		keyword, hasKeyword := keywords[name]
+		if hasKeyword {
+			bound[name] = true
+		}
+
+		var arg object.Object
+		switch {
+		case paramIdx < len(args):
+			if hasKeyword {
+				return nil, newError("multiple values for argument %s", name)
+			}
+			arg = args[paramIdx]
+		case hasKeyword:
+			arg = keyword
+		case def != nil:
+			arg = Eval(def, env)
+			if err, ok := arg.(*object.Error); ok {
+				return nil, err
+			}
+		default:
+			return nil, newError("missing argument for parameter %s", pattern)
+		}
+
+		if err := bindPattern(pattern, arg, env); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(bound) < len(keywords) {
+		unknown := []string{}
+		for name := range keywords {
+			if !bound[name] {
+				unknown = append(unknown, name)
+			}
+		}
+		sort.Strings(unknown)
+		return nil, newError("unexpected keyword argument %s", unknown[0])
+	}
+
+	if rest != nil && rest.Name != nil {
+		remaining := []object.Object{}
+		if len(args) > len(params) {
+			remaining = append(remaining, args[len(params):]...)
+		}
+		env.Set(rest.Name.Value, &object.Array{Elements: remaining})
 	}
 
-	return env
+	return env, nil
+}
+
+// hasDefault reports whether any of params has a default value, so that
+// calls may pass fewer arguments than there are parameters.
+func hasDefault(params []ast.Pattern) bool {
+	for _, param := range params {
+		if _, ok := param.(*ast.DefaultPattern); ok {
+			return true
+		}
+	}
+	return false
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -376,6 +1279,8 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.MAP_OBJ:
 		return evalMapIndexExpression(left, index)
 	default:
@@ -399,13 +1304,155 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
+// strings are indexed by character, not by byte
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+	idx := index.(*object.Integer).Value
+	max := int64(len(runes) - 1)
+
+	if idx < 0 {
+		idx = max + idx + 1
+	}
+
+	if idx < 0 || idx > max {
+		return NULL
+	}
+
+	return &object.String{Value: string(runes[idx])}
+}
+
+func evalRangeExpression(re *ast.RangeExpression, env *object.Environment) object.Object {
+	start := Eval(re.Start, env)
+	if isError(start) {
+		return start
+	}
+
+	end := Eval(re.End, env)
+	if isError(end) {
+		return end
+	}
+
+	if start.Type() != object.INTEGER_OBJ || end.Type() != object.INTEGER_OBJ {
+		return newError("range bounds must be INTEGER, got %s and %s", start.Type(), end.Type())
+	}
+
+	return &object.Range{
+		Start:     start.(*object.Integer).Value,
+		End:       end.(*object.Integer).Value,
+		Inclusive: re.Inclusive,
+	}
+}
+
+// rangeLast returns the last integer in r, or false if r is empty.
+func rangeLast(r *object.Range) (int64, bool) {
+	if r.Inclusive {
+		return r.End, r.Start <= r.End
+	}
+	return r.End - 1, r.Start < r.End
+}
+
+func evalSliceExpression(se *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(se.Left, env)
+	if isError(left) {
+		return left
+	}
+	if se.Optional && left == NULL {
+		return NULL
+	}
+
+	var length int
+	switch left := left.(type) {
+	case *object.Array:
+		length = len(left.Elements)
+	case *object.String:
+		length = utf8.RuneCountInString(left.Value)
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+
+	start, err := evalSliceBound(se.Start, 0, length, env)
+	if err != nil {
+		return err
+	}
+	end, err := evalSliceBound(se.End, length, length, env)
+	if err != nil {
+		return err
+	}
+	if end < start {
+		end = start
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		elements := make([]object.Object, end-start)
+		copy(elements, left.Elements[start:end])
+		return &object.Array{Elements: elements}
+	default:
+		runes := []rune(left.(*object.String).Value)
+		return &object.String{Value: string(runes[start:end])}
+	}
+}
+
+// evalSliceBound evaluates one bound of a slice of something length long.
+// Negative bounds count from the end, like single indexes, and bounds past
+// either end are clamped.
+func evalSliceBound(
+	node ast.Expression,
+	def, length int,
+	env *object.Environment,
+) (int, object.Object) {
+	if node == nil {
+		return def, nil
+	}
+
+	bound := Eval(node, env)
+	if isError(bound) {
+		return 0, bound
+	}
+
+	integer, ok := bound.(*object.Integer)
+	if !ok {
+		return 0, newError("slice index must be INTEGER, got %s", bound.Type())
+	}
+
+	idx := integer.Value
+	if idx < 0 {
+		idx += int64(length)
+	}
+
+	switch {
+	case idx < 0:
+		return 0, nil
+	case idx > int64(length):
+		return length, nil
+	default:
+		return int(idx), nil
+	}
+}
+
 func evalMapLiteral(
 	node *ast.MapLiteral,
 	env *object.Environment,
 ) object.Object {
 	pairs := make(map[object.HashKey]object.MapPair)
 
-	for keyNode, valueNode := range node.Pairs {
+	for _, keyNode := range node.OrderedKeys() {
+		if spread, ok := keyNode.(*ast.SpreadElement); ok {
+			value := Eval(spread.Value, env)
+			if isError(value) {
+				return value
+			}
+			m, ok := value.(*object.Map)
+			if !ok {
+				return newErrorAt(spread, "cannot spread %s into a map", value.Type())
+			}
+			for hashed, pair := range m.Pairs {
+				pairs[hashed] = pair
+			}
+			continue
+		}
+
+		valueNode := node.Pairs[keyNode]
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
@@ -428,6 +1475,92 @@ func evalMapLiteral(
 	return &object.Map{Pairs: pairs}
 }
 
+func evalListComprehension(lc *ast.ListComprehension, env *object.Environment) object.Object {
+	elements := []object.Object{}
+
+	err := evalComprehensionClause(lc.Clause, env, func(scope *object.Environment) object.Object {
+		element := Eval(lc.Element, scope)
+		if isError(element) {
+			return element
+		}
+		elements = append(elements, element)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+func evalMapComprehension(mc *ast.MapComprehension, env *object.Environment) object.Object {
+	pairs := make(map[object.HashKey]object.MapPair)
+
+	err := evalComprehensionClause(mc.Clause, env, func(scope *object.Environment) object.Object {
+		key := Eval(mc.Key, scope)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hashable key: %s", key.Type())
+		}
+
+		value := Eval(mc.Value, scope)
+		if isError(value) {
+			return value
+		}
+
+		pairs[hashKey.HashKey()] = object.MapPair{Key: key, Value: value}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return &object.Map{Pairs: pairs}
+}
+
+// evalComprehensionClause calls each for every element of the clause's
+// iterable that passes its condition. The loop variables are bound in a
+// fresh scope for each element, so they don't leak into env and closures
+// built by the comprehension each see their own values. It returns the
+// first error, either from the clause or from each.
+func evalComprehensionClause(
+	clause *ast.ComprehensionClause,
+	env *object.Environment,
+	each func(scope *object.Environment) object.Object,
+) object.Object {
+	iterable := Eval(clause.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	return forEach(clause.Iterable, iterable, clause.Key == nil, func(key, value object.Object) (object.Object, bool) {
+		scope := object.NewEnclosedEnvironment(env)
+		if clause.Key != nil {
+			scope.Set(clause.Key.Value, key)
+		}
+		scope.Set(clause.Value.Value, value)
+
+		if clause.Condition != nil {
+			condition := Eval(clause.Condition, scope)
+			if isError(condition) {
+				return condition, true
+			}
+			if !isTruthy(condition) {
+				return nil, false
+			}
+		}
+
+		if err := each(scope); err != nil {
+			return err, true
+		}
+		return nil, false
+	})
+}
+
 func evalMapIndexExpression(_map, index object.Object) object.Object {
 	mapObject := _map.(*object.Map)
 
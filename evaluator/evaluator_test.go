@@ -27,6 +27,29 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 +-10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"7 % -3", 1},
+		{"2 + 10 % 4 * 3", 8},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 3", -8},
+		{"5 ** 0", 1},
+		{"1 ** 99999999999999999999", 1},
+		{"(-1) ** 10000000001", -1},
+		{"0 ** 10000000000", 0},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"-1 & 255", 255},
+		{"1 << 10", 1024},
+		{"1024 >> 3", 128},
+		{"-16 >> 2", -4},
+		{"1 >> 70", 0},
+		{"1 | 2 ^ 3 & 4", 1 | 2 ^ 3&4},
+		{"1 + 2 << 3", 24},
+		{"(1 << 64) >> 60", 16},
 	}
 
 	for _, tt := range tests {
@@ -35,6 +58,124 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"2.5", 2.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"10 / 4.0", 2.5},
+		{"3 * 1.5 - 1", 3.5},
+		{"1e3 / 8", 125},
+		{"let total = 7; let count = 2; total / float(count)", 3.5},
+		{"7.5 % 2", 1.5},
+		{"2 ** -1", 0.5},
+		{"2.0 ** 3", 8},
+		{"4 ** 0.5", 2},
+		{"2 ** -99999999999999999999 * 0", 0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestBigIntegerPromotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"4294967296 * 4294967296", "18446744073709551616"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+		{"123456789012345678901234567890", "123456789012345678901234567890"},
+		{"123456789012345678901234567890 * 10 + 1", "1234567890123456789012345678901"},
+		{`
+let factorial = fn(n) { if (n < 2) { 1 } else { n * factorial(n - 1) } };
+factorial(25)`, "15511210043330985984000000"},
+		{`int("99999999999999999999")`, "99999999999999999999"},
+		{`int(1e20)`, "100000000000000000000"},
+		{"2 ** 64", "18446744073709551616"},
+		{"10 ** 20 % 7 + 10 ** 20", "100000000000000000002"},
+		{"1 << 64", "18446744073709551616"},
+		{"99999999999999999999 | 1", "99999999999999999999"},
+		{"-(1 << 70) >> 1", "-590295810358705651712"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		result, ok := evaluated.(*object.BigInteger)
+		if !ok {
+			t.Errorf("object is not BigInteger. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("object has wrong value. got=%s, want=%s", result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestBigIntegerDemotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"9223372036854775807 + 1 - 1", 9223372036854775807},
+		{"(4294967296 * 4294967296) / 4294967296", 4294967296},
+		{"-9223372036854775808", -9223372036854775808},
+		{"let big = 99999999999999999999; big - big", 0},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBigIntegerComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"99999999999999999999 > 1", true},
+		{"1 < 99999999999999999999", true},
+		{"99999999999999999999 == 99999999999999999999", true},
+		{"99999999999999999999 != 99999999999999999998", true},
+		{"99999999999999999999 == 1", false},
+		{"99999999999999999999 >= 99999999999999999999", true},
+		{"99999999999999999999 <= 1", false},
+		{"99999999999999999999 > 1.5", true},
+		{"-99999999999999999999 < -1e19", true},
+		{`{99999999999999999999: true}[99999999999999999999]`, true},
+		{`{1e20: true}[100000000000000000000]`, true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got %T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g",
+			result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
 func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -64,6 +205,16 @@ func TestEvalBooleanExpression(t *testing.T) {
 		input    string
 		expected bool
 	}{
+		{"1 <= 2", true},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1.5 >= 1", true},
+		{"1 <= 0.5", false},
 		{"true", true},
 		{"false", false},
 		{"1 < 2", true},
@@ -83,6 +234,11 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 == 1.0", true},
+		{"1.0 != 1", false},
+		{"0.1 + 0.2 == 0.3", false},
+		{"1 < 1.5", true},
+		{"2.5 > 3", false},
 	}
 
 	for _, tt := range tests {
@@ -138,6 +294,11 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 }", nil},
+		{"if (false) { 1 } else if (false) { 2 } else if (true) { 3 } else { 4 }", 3},
+		{"let f = fn(x) { if (x == 0) { 0 } else if (x == 1) { return 1; } else { 2 } }; f(1)", 1},
 	}
 
 	for _, tt := range tests {
@@ -151,6 +312,242 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 }
 
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 5) { let i = i + 1; } i", 5},
+		{"let i = 0; while (i < 5) { let i = i + 1; }", nil},
+		{"let i = 10; while (false) { let i = 0; } i", 10},
+		{"let s = 0; for (let i = 0; i < 5; let i = i + 1) { let s = s + i; } s", 10},
+		{"let s = 0; for (x in [1, 2, 3]) { let s = s + x; } s", 6},
+		{"let s = 0; for (i, x in [5, 5, 5]) { let s = s + i; } s", 3},
+		{`let s = ""; for (c in "héllo") { let s = c + s; } s`, "olléh"},
+		{`let s = 0; for (k in {1: "a", 2: "b"}) { let s = s + k; } s`, 3},
+		{`let s = ""; for (k, v in {1: "a"}) { let s = "${k}${v}"; } s`, "1a"},
+		{"let s = 0; for (x in []) { let s = 1; } s", 0},
+		{"let i = 0; while (true) { let i = i + 1; if (i == 3) { break; } } i", 3},
+		{"let i = 0; for (;;) { let i = i + 1; if (i > 9) { break } } i", 10},
+		{"let s = 0; for (x in [1, 2, 3, 4]) { if (x == 2) { continue; } let s = s + x; } s", 8},
+		{"let s = 0; for (let i = 0; i < 4; let i = i + 1) { if (i == 1) { continue } let s = s + i; } s", 5},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10; } } }; f()", 20},
+		{"let f = fn() { while (true) { return 7; } }; f()", 7},
+		{"let f = fn() { while (true) { break; } 1 }; f()", 1},
+		{"let s = 0; for (x in [1, 2]) { for (y in [10, 20]) { if (y == 20) { break } let s = s + x * y; } } s", 30},
+		{"let s = 0; for (x in [1, 2, 3]) { if (x == 1) { continue } else if (x == 3) { break } s += x; } s", 2},
+		{"let s = 0; for (x in [1, 2]) { let y = if (x > 1) { while (true) { break } 5 } else { x }; s += y; } s", 6},
+		{"let s = 0; for (x in [1, 2]) { s += if (true) { switch (x) { case 1: break } x }; } s", 3},
+		{"let s = 0; for (i in 0..5) { s += i; } s", 10},
+		{"let s = 0; for (i in 1..=5) { s += i; } s", 15},
+		{"let s = 0; for (i in 5..1) { s += 1; } s", 0},
+		{"let s = 0; for (i in 3..3) { s += 1; } s", 0},
+		{"let s = 0; for (i in 3..=3) { s += 1; } s", 1},
+		{"let s = 0; for (n, i in 10..13) { s += n; } s", 3},
+		{"let s = 0; for (i in 0..1000000000000) { if (i == 3) { break } s += i; } s", 3},
+		{"let s = 0; for (i in 9223372036854775806..=9223372036854775807) { s += 1; } s", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestSwitchStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"switch (2) { case 1: 10 case 2: 20 case 3: 30 }", 20},
+		{"switch (3) { case 1, 2: 10 case 3, 4: 30 }", 30},
+		{"switch (5) { case 1: 10 default: 0 }", 0},
+		{"switch (5) { default: 0 case 5: 50 }", 50},
+		{"switch (5) { case 1: 10 }", nil},
+		{"switch (2.0) { case 2: 20 }", 20},
+		{"switch (2) { case 2.0: 20 }", 20},
+		{`switch ("b") { case "a": 1 case "b": 2 }`, 2},
+		{`switch ("1") { case 1: 1 default: 2 }`, 2},
+		{"switch (true) { case 1 > 2: 1 case 1 < 2: 2 }", 2},
+		{"let x = 0; switch (1) { case 1: x += 1; fallthrough; case 2: x += 10 case 3: x += 100 } x", 11},
+		{"let x = 0; switch (9) { default: x += 1; fallthrough; case 2: x += 10 } x", 11},
+		{"let x = 0; switch (1) { case 1: x = 1; break; x = 2 } x", 1},
+		{"let x = 0; switch (1) { case 1: }", nil},
+		{"let n = 0; for (i in [1, 2, 3]) { switch (i) { case 2: break; default: n += i } } n", 4},
+		{"let n = 0; for (i in [1, 2, 3]) { switch (i) { case 2: continue; } n += i } n", 4},
+		{"let f = fn(x) { switch (x) { case 1: return 10; } 0 }; f(1)", 10},
+		{"let f = fn(x) { switch (x) { case 1: return 10; } 0 }; f(2)", 0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = 2", 2},
+		{"let x = 5; x += 3; x", 8},
+		{"let x = 5; x -= 3; x", 2},
+		{"let x = 5; x *= 3; x", 15},
+		{"let x = 15; x /= 3; x", 5},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{"let a = 1; let b = 2; a = b = 7; a + b", 14},
+		{"let x = 1; let f = fn() { x = 10; }; f(); x", 10},
+		{"let x = 1; let f = fn() { let x = 2; x = 3; }; f(); x", 1},
+		{"let counter = fn() { let c = 0; fn() { c += 1 } }; let next = counter(); next(); next(); next()", 3},
+		{"let i = 0; let s = 0; while (i < 4) { s += i; i += 1; } s", 6},
+		{"let s = 0; for (let i = 0; i < 4; i += 1) { s += i; } s", 6},
+		{"let a = [1, 2, 3]; a[0] = 10; a[0]", 10},
+		{"let a = [1, 2, 3]; a[-1] = 30; a[2]", 30},
+		{"let a = [1, 2, 3]; a[1] += 5; a[1]", 7},
+		{"let a = [1, 2]; let b = a; b[0] = 9; a[0]", 9},
+		{`let m = {"a": 1}; m["a"] = 2; m["a"]`, 2},
+		{`let m = {}; m["b"] = 3; m["b"]`, 3},
+		{`let m = {"n": 1}; m["n"] *= 4; m["n"]`, 4},
+		{"let grid = [[1, 2], [3, 4]]; grid[1][0] = 30; grid[1][0]", 30},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			}
+		}
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"true || false", true},
+		{"1 && 2", 2},
+		{"0 && 2", 2},
+		{"false && 2", false},
+		{"1 || 2", 1},
+		{`let name = if (false) { "x" }; name || "default"`, "default"},
+		{`"given" || "default"`, "given"},
+		{`let default = "d"; false || default`, "d"},
+		{"false || 1 && 5", 5},
+		{"false && nothing()", false},
+		{"true || nothing()", true},
+		{"let calls = 0; let f = fn() { calls += 1; true }; false && f(); true || f(); calls", 0},
+		{"let calls = 0; let f = fn() { calls += 1; true }; true && f(); false || f(); calls", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			}
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`match (1) { 0 => "zero", 1 => "one", _ => "many" }`, "one"},
+		{`match (5) { 0 => "zero", 1 => "one", _ => "many" }`, "many"},
+		{`match (-3) { -3 => "minus three", _ => "other" }`, "minus three"},
+		{`match (2.0) { 2 => "two", _ => "other" }`, "two"},
+		{`match (99999999999999999999) { 99999999999999999999 => "big", _ => "other" }`, "big"},
+		{`match ("b") { "a" => 1, "b" => 2 }`, 2},
+		{`match (true) { false => 0, true => 1 }`, 1},
+		{"match (7) { n => n * 2 }", 14},
+		{"let n = 1; match (7) { n => n }; n", 1},
+		{`match (4) { n if n % 2 == 1 => "odd", n if n % 2 == 0 => "even" }`, "even"},
+		{`match (3) { n if n > 5 => "big", n => "small" }`, "small"},
+		{"match ([]) { [] => 0, [x] => x, _ => 99 }", 0},
+		{"match ([4]) { [] => 0, [x] => x, _ => 99 }", 4},
+		{"match ([4, 5]) { [] => 0, [x] => x, _ => 99 }", 99},
+		{"match ([1, 2, 3]) { [first, ...rest] => first + len(rest) }", 3},
+		{"match ([1]) { [first, ...rest] => len(rest) }", 0},
+		{"match ([]) { [first, ...rest] => 1, [...] => 2 }", 2},
+		{"match ([1, [2, 3]]) { [a, [b, c]] => a + b + c }", 6},
+		{"match ([1, 2]) { [1, x] => x, _ => 0 }", 2},
+		{"match ([2, 2]) { [1, x] => x, _ => 0 }", 0},
+		{"match (5) { [x] => x, _ => 0 }", 0},
+		{`match ({"name": "lemon", "age": 3}) { {"age": a} => a }`, 3},
+		{`match ({"name": "lemon"}) { {name} => name }`, "lemon"},
+		{`match ({"name": "lemon"}) { {"age": a} => a, {"name": "lemon"} => "found" }`, "found"},
+		{`match ({1: [1, 2]}) { {1: [_, x]} => x }`, 2},
+		{`match ({"a": 1}) { {"a": 2} => "two", {"a": 1} => "one" }`, "one"},
+		{`let describe = fn(x) { match (x) { [] => "empty", [_] => "single", _ => "many" } }; describe([1]) + describe([])`, "singleempty"},
+		{`let sum = fn(xs) { match (xs) { [] => 0, [x, ...rest] => x + sum(rest) } }; sum([1, 2, 3, 4])`, 10},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			}
+		}
+	}
+}
+
 func testNullObject(t *testing.T, obj object.Object) bool {
 	if obj != NULL {
 		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
@@ -235,10 +632,238 @@ if (10 > 1) {
 			`"Hello" - "World"`,
 			"unknown operator: STRING - STRING",
 		},
+		{
+			`"a" < "b"`,
+			"unknown operator: STRING < STRING",
+		},
 		{
 			`{"name": "lemon"}[fn(x) { x }];`,
 			"unusable as hashable key: FUNCTION",
 		},
+		{
+			"for (x in 5) { x }",
+			"cannot iterate over INTEGER",
+		},
+		{
+			"x = 5",
+			"identifier not found: x",
+		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"match (3) { 1 => 1, 2 => 2 }",
+			"non-exhaustive match: no arm matches 3",
+		},
+		{
+			`match ([1]) { [x] if x + "a" => 1 }`,
+			"type mismatch: INTEGER + STRING",
+		},
+		{
+			"let x = 5; x % (2 - 2)",
+			"modulo by zero",
+		},
+		{
+			"99999999999999999999 / 0",
+			"division by zero",
+		},
+		{
+			"99999999999999999999 % 0",
+			"modulo by zero",
+		},
+		{
+			"1.0 / 0",
+			"division by zero",
+		},
+		{
+			"1 / 0.0",
+			"division by zero",
+		},
+		{
+			"5 % 0.0",
+			"modulo by zero",
+		},
+		{
+			"0 ** -1",
+			"division by zero",
+		},
+		{
+			"0.0 ** -0.5",
+			"division by zero",
+		},
+		{
+			"1 >> -1",
+			"negative shift count: -1",
+		},
+		{
+			"1 << 99999999999999999999",
+			"shift count too large: 99999999999999999999",
+		},
+		{
+			"2 ** 10000000000",
+			"exponent too large: 10000000000",
+		},
+		{
+			"-3 ** 99999999999999999999",
+			"exponent too large: 99999999999999999999",
+		},
+		{
+			"1.5 & 1",
+			"unknown operator: FLOAT & INTEGER",
+		},
+		{
+			"let a = [1]; a[1] = 2",
+			"index out of range: 1",
+		},
+		{
+			`let a = [1]; a["0"] = 2`,
+			"array index must be INTEGER, got STRING",
+		},
+		{
+			`let m = {}; m[[1]] = 2`,
+			"unusable as hashable key: ARRAY",
+		},
+		{
+			`let s = "abc"; s[0] = "x"`,
+			"index assignment not supported: STRING",
+		},
+		{
+			`let x = 1; x += "a"`,
+			"type mismatch: INTEGER + STRING",
+		},
+		{
+			"while (true) { 1 + true; }",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"for (let i = 0; i < 3; let i = i + foo) { }",
+			"identifier not found: foo",
+		},
+		{
+			"let [a, b] = 5;",
+			"cannot destructure INTEGER with array pattern [a, b]",
+		},
+		{
+			"let [a, b] = [1, 2, 3];",
+			"array pattern [a, b] needs 2 elements, got 3",
+		},
+		{
+			"let [a, b, ...rest] = [1];",
+			"array pattern [a, b, ...rest] needs at least 2 elements, got 1",
+		},
+		{
+			`let {"name": n} = [1];`,
+			`cannot destructure ARRAY with map pattern {"name": n}`,
+		},
+		{
+			`let {"name": n, "age": a} = {"name": "x"};`,
+			`key "age" not found for map pattern {"name": n, "age": a}`,
+		},
+		{
+			"let [0, x] = [1, 2];",
+			"1 does not match pattern 0",
+		},
+		{
+			"let f = fn([x, y]) { x + y }; f([1]);",
+			"array pattern [x, y] needs 2 elements, got 1",
+		},
+		{
+			`[1, 2][0:"1"]`,
+			"slice index must be INTEGER, got STRING",
+		},
+		{
+			`let c = {}; c["a"]["b"]`,
+			"index operator not supported: NULL",
+		},
+		{
+			`let c = {}; c["a"]?.["b"]["c"]`,
+			"index operator not supported: NULL",
+		},
+		{
+			"[1].foo()",
+			"unknown method foo for ARRAY",
+		},
+		{
+			"[1].len",
+			"field access not supported: ARRAY",
+		},
+		{
+			"let a = [1]; a.x = 2",
+			"field assignment not supported: ARRAY",
+		},
+		{
+			"5.push(1)",
+			"unknown method push for INTEGER",
+		},
+		{
+			"[...5]",
+			"cannot spread INTEGER into a list",
+		},
+		{
+			"{...[1]}",
+			"cannot spread ARRAY into a map",
+		},
+		{
+			"len(...5)",
+			"cannot spread INTEGER into a list",
+		},
+		{
+			"[x for x in 5]",
+			"cannot iterate over INTEGER",
+		},
+		{
+			"[x for x in [1]]; x",
+			"identifier not found: x",
+		},
+		{
+			"[x for x in [1] if y]",
+			"identifier not found: y",
+		},
+		{
+			"{[x]: x for x in [1]}",
+			"unusable as hashable key: ARRAY",
+		},
+		{
+			"5[1:]",
+			"slice operator not supported: INTEGER",
+		},
+		{
+			`1.."a"`,
+			"range bounds must be INTEGER, got INTEGER and STRING",
+		},
+		{
+			"let f = fn(x, y) { x }; f(1);",
+			"missing argument for parameter y",
+		},
+		{
+			"let f = fn(x) { x }; f(1, 2);",
+			"wrong number of arguments. got=2, want=1",
+		},
+		{
+			"let f = fn(x, y) { x }; f(1, x: 2);",
+			"multiple values for argument x",
+		},
+		{
+			"let f = fn(x, y = 1) { x }; f(1, z: 2, w: 3);",
+			"unexpected keyword argument w",
+		},
+		{
+			"let f = fn(x, [y]) { x }; f(1, [2], y: 9);",
+			"unexpected keyword argument y",
+		},
+		{
+			"let f = fn(x, y = 1) { x }; f(1, 2, 3);",
+			"wrong number of arguments. got=3, want=at most 2",
+		},
+		{
+			"let f = fn(x = y) { x }; f();",
+			"identifier not found: y",
+		},
+		{
+			"len(x: [1])",
+			"builtin functions don't take keyword arguments",
+		},
 	}
 
 	for _, tt := range tests {
@@ -267,6 +892,214 @@ func TestLetStatements(t *testing.T) {
 		{"let a = 5 * 5; a;", 25},
 		{"let a = 5; let b = a; b;", 5},
 		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+		{"let [a, b] = [1, 2]; a * 10 + b;", 12},
+		{"let [a, ...rest] = [1, 2, 3]; a + len(rest);", 3},
+		{"let [_, [b, c]] = [1, [2, 3]]; b + c;", 5},
+		{`let {"x": x, "y": y} = {"x": 4, "y": 5, "z": 6}; x * y;`, 20},
+		{`let {x, "p": [a, b]} = {"x": 1, "p": [2, 3]}; x + a + b;`, 6},
+		{"let f = fn([a, b], c) { a + b + c }; f([1, 2], 3);", 6},
+		{`let f = fn({"n": n}) { n * 2 }; f({"n": 21});`, 42},
+		{"let f = fn([x, ...xs]) { if (len(xs) == 0) { x } else { x + f(xs) } }; f([1, 2, 3]);", 6},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4][2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3, 4][:-1]", "[1, 2, 3]"},
+		{"[1, 2, 3, 4][-2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][3:1]", "[]"},
+		{"[1, 2, 3, 4][-10:10]", "[1, 2, 3, 4]"},
+		{"let a = [1, 2, 3]; let b = a[:]; b[0] = 9; a", "[1, 2, 3]"},
+		{`"héllo"[1:3]`, "él"},
+		{`"hello"[:-1]`, "hell"},
+		{`"hello"[10:]`, ""},
+		{"1..5", "1..5"},
+		{"let n = 3; 0..=n * 2", "0..=6"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		var got string
+		if str, ok := evaluated.(*object.String); ok {
+			got = str.Value
+		} else {
+			got = evaluated.Inspect()
+		}
+		if got != tt.expected {
+			t.Errorf("wrong result for %q. got=%q, want=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestNullSafeOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let c = {"a": [1, 2]}; c?.["a"]?.[1]`, 2},
+		{`let c = {"a": [1, 2]}; c?.["b"]?.[1]`, nil},
+		{`let c = {"a": [1, 2]}; c?.["b"]?.[1] ?? 7`, 7},
+		{`let c = {}; c["x"]?.[0:1]`, nil},
+		{"let f = fn() { 3 }; f?.()", 3},
+		{`let m = {}; m["f"]?.(undefined)`, nil},
+		{`let m = {"f": fn(x) { x * 2 }}; m["f"]?.(4)`, 8},
+		{`let m = {}; 1 |> m["f"]?.()`, nil},
+		{"0 ?? 5", 0},
+		{"false ?? 5", false},
+		{`let m = {}; m["n"] ?? 5`, 5},
+		{`let m = {}; m["a"] ?? m["b"] ?? 9`, 9},
+		{"1 ?? undefined", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestSpreadElements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1, 2]; let b = [4]; [...a, 3, ...b]", "[1, 2, 3, 4]"},
+		{"[...[]]", "[]"},
+		{`[..."héy"]`, "[h, é, y]"},
+		{"[...0..3, ...1..=1]", "[0, 1, 2, 1]"},
+		{"let a = [1]; let b = [...a]; b[0] = 2; a", "[1]"},
+		{`let d = {"k": 1}; {...d}`, "{k: 1}"},
+		{`let d = {"k": 1}; {...d, "k": 2}`, "{k: 2}"},
+		{`let d = {"k": 1}; {"k": 2, ...d}`, "{k: 1}"},
+		{`let d = {"k": 1}; let e = {...d}; e.k = 2; d`, "{k: 1}"},
+		{"let f = fn(a, b, c) { [c, b, a] }; let args = [1, 2]; f(...args, 3)", "[3, 2, 1]"},
+		{"let f = fn(...xs) { xs }; f(...[1, 2], ...[3])", "[1, 2, 3]"},
+		{"[1, 2] |> push(...[3])", "[1, 2, 3]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. got=%s, want=%s", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestComprehensions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let xs = [1, -2, 3]; [x * 2 for x in xs if x > 0]", "[2, 6]"},
+		{"[x for x in []]", "[]"},
+		{"[i * i for i in 1..=4 if i % 2 == 0]", "[4, 16]"},
+		{`[c for i, c in "abc" if i > 0]`, "[b, c]"},
+		{`let m = {"a": 1}; {k: v * 10 for k, v in m}`, "{a: 10}"},
+		{`let m = {"a": 1, "b": 2}; {k: v for k, v in m if v > 1}.keys()`, "[b]"},
+		{`let m = {"a": 1}; [k for k in m]`, "[a]"},
+		{`{x: x * x for x in 0..4}[3]`, "9"},
+		{"[[y for y in 0..x] for x in [1, 2]]", "[[0], [0, 1]]"},
+		{"let x = 1; [x for x in [5]]; x", "1"},
+		{"let n = 10; [x + n for x in [1, 2]]", "[11, 12]"},
+		{"let fs = [fn() { i } for i in 0..3]; [fs[0](), fs[2]()]", "[0, 2]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. got=%s, want=%s", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let m = {"a": 1}; m.a`, 1},
+		{`let m = {"a": {"b": 2}}; m.a.b`, 2},
+		{`let m = {"a": 1}; m.b`, nil},
+		{`let m = {}; m?.a?.b`, nil},
+		{`let m = {"a": 1}; m.a = 5; m["a"]`, 5},
+		{`let m = {}; m.n = 1; m.n += 2; m.n`, 3},
+		{`let m = {"double": fn(x) { x * 2 }}; m.double(4)`, 8},
+		{`let m = {"len": fn() { 99 }}; m.len()`, 99},
+		{`let m = {"a": 1, "b": 2}; m.keys().len()`, 2},
+		{`"héllo".len()`, 5},
+		{"[1, 2, 3].push(4).len()", 4},
+		{"let a = [3, 1, 2]; a.sorted().first()", 1},
+		{`"42".int() + 1`, 43},
+		{"([1, 2] |> [0].merged()).len()", 3},
+		{`let m = {}; m.f?.()`, nil},
+		{"let m = {}; m.x?.f(undefined)", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestPipeExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let sub = fn(a, b) { a - b }; 10 |> sub(3);", 7},
+		{"let inc = fn(x) { x + 1 }; 1 |> inc |> inc;", 3},
+		{"let inc = fn(x) { x + 1 }; 1 |> inc();", 2},
+		{"[1, 2, 3] |> push(4) |> len;", 4},
+		{"let add = fn(a, b = 0, c = 0) { a + b + c }; 1 |> add(c: 10);", 11},
+		{"let mk = fn(n) { fn(x) { x * n } }; let triple = mk(3); 5 |> triple;", 15},
+		{"let x = 2 + 1 |> fn(n) { n * n }; x;", 9},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestFunctionParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let f = fn(x, y = 10) { x + y }; f(1);", 11},
+		{"let f = fn(x, y = 10) { x + y }; f(1, 2);", 3},
+		{"let f = fn(x, y = x * 2) { x + y }; f(3);", 9},
+		{"let f = fn(x = 1, y = 2) { x * 10 + y }; f(y: 5);", 15},
+		{"let f = fn(x, y) { x * 10 + y }; f(y: 1, x: 2);", 21},
+		{"let f = fn(x, y) { x * 10 + y }; f(3, y: 4);", 34},
+		{"let f = fn(first, ...others) { first + len(others) }; f(10, 1, 2, 3);", 13},
+		{"let f = fn(first, ...others) { len(others) }; f(10);", 0},
+		{"let f = fn(...xs) { len(xs) }; f();", 0},
+		{"let f = fn(x, ...) { x }; f(1, 2, 3);", 1},
+		{"let f = fn([a, b] = [1, 2]) { a + b }; f();", 3},
+		{"let f = fn(x, y = 1, ...rest) { x + y + len(rest) }; f(1, y: 5);", 6},
+		{"let g = fn(x, y = 2) { x * y }; let h = fn(f) { f(3) }; h(g);", 6},
 	}
 
 	for _, tt := range tests {
@@ -357,6 +1190,25 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestStringComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`"héllo" != "héllo"`, false},
+		{`"" == ""`, true},
+		{`let s = "ab"; s + "c" == "abc"`, true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -538,6 +1390,22 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{false: 5}[false]`,
 			5,
 		},
+		{
+			`{1: 5}[1.0]`,
+			5,
+		},
+		{
+			`{2.0: 5}[2]`,
+			5,
+		},
+		{
+			`{2.5: 5}[2.5]`,
+			5,
+		},
+		{
+			`{2.5: 5}[2]`,
+			nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -550,3 +1418,106 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedLine   int
+		expectedColumn int
+	}{
+		{"foobar", 1, 1},
+		{"let x = 1;\nlet y = x + true;", 2, 11},
+		{"let f = fn() {\n  missing\n};\nf();", 2, 3},
+		{`len(1)`, 1, 4},
+		{"[1, ...5]", 1, 5},
+		{"len(1, ...true)", 1, 8},
+		{`{"a": 1, ...[2]}`, 1, 10},
+		{"let x = 1;\n[y for y in x]", 2, 13},
+		{"{y: y for y in true}", 1, 16},
+		{"for (a in 5) { a }", 1, 11},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Pos.Line != tt.expectedLine || errObj.Pos.Column != tt.expectedColumn {
+			t.Errorf("wrong error position for %q. expected=%d:%d, got=%d:%d",
+				tt.input, tt.expectedLine, tt.expectedColumn,
+				errObj.Pos.Line, errObj.Pos.Column)
+		}
+	}
+}
+
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"hello"[0]`, "h"},
+		{`"héllo"[1]`, "é"},
+		{`"日本語"[2]`, "語"},
+		{`"日本語"[-1]`, "語"},
+		{`"日本語"[3]`, nil},
+		{`let größe = "groß"; größe[3]`, "ß"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		expected, ok := tt.expected.(string)
+		if !ok {
+			testNullObject(t, evaluated)
+			continue
+		}
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != expected {
+			t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+		}
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let price = 3; let qty = 4; "total: ${price * qty}"`, "total: 12"},
+		{`let name = "lemon"; "hello, ${name}!"`, "hello, lemon!"},
+		{`"${[1, 2]} ${true} ${"nested ${1 + 1}"}"`, "[1, 2] true nested 2"},
+		{`"\${not} interpolated"`, "${not} interpolated"},
+		{`let f = fn(x) { x * 2 }; """
+doubled: ${f(21)}"""`, "doubled: 42"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. got=%q, want=%q", str.Value, tt.expected)
+		}
+	}
+
+	evaluated := testEval(`"value: ${missing}"`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "identifier not found: missing" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
@@ -1,8 +1,10 @@
 package evaluator
 
 import (
+	"fmt"
 	"lemon/ast"
 	"lemon/object"
+	"sort"
 )
 
 func isMacroDefinition(node ast.Statement) bool {
@@ -11,6 +13,11 @@ func isMacroDefinition(node ast.Statement) bool {
 		return false
 	}
 
+	_, ok = letStatement.Name.(*ast.Identifier)
+	if !ok {
+		return false
+	}
+
 	_, ok = letStatement.Value.(*ast.MacroLiteral)
 	if !ok {
 		return false
@@ -29,13 +36,29 @@ func addMacro(stmt ast.Statement, env *object.Environment) {
 		Body:       macroLiteral.Body,
 	}
 
-	env.Set(letStatement.Name.Value, macro)
+	name, _ := letStatement.Name.(*ast.Identifier)
+	env.Set(name.Value, macro)
 }
 
 func ExpandMacros(program ast.Node, env *object.Environment) ast.Node {
 	return ast.Modify(program, func(node ast.Node) ast.Node {
-		callExpression, ok := node.(*ast.CallExpression)
-		if !ok {
+		var callExpression *ast.CallExpression
+
+		switch node := node.(type) {
+		case *ast.CallExpression:
+			callExpression = node
+		case *ast.PipeExpression:
+			// x |> m(a) expands m(x, a)
+			call, ok := node.Right.(*ast.CallExpression)
+			if !ok {
+				return node
+			}
+			callExpression = &ast.CallExpression{
+				Token:     call.Token,
+				Function:  call.Function,
+				Arguments: append([]ast.Expression{node.Left}, call.Arguments...),
+			}
+		default:
 			return node
 		}
 
@@ -44,8 +67,8 @@ func ExpandMacros(program ast.Node, env *object.Environment) ast.Node {
 			return node
 		}
 
-		args := quoteArgs(callExpression)
-		evalEnv := extendMacroEnv(macro, args)
+		args, keywords := quoteArgs(callExpression)
+		evalEnv := extendMacroEnv(macro, args, keywords)
 
 		evaluated := Eval(macro.Body, evalEnv)
 
@@ -80,24 +103,72 @@ func isMacroCall(
 	return macro, true
 }
 
-func quoteArgs(exp *ast.CallExpression) []*object.Quote {
+// quoteArgs quotes the arguments of a macro call, returning keyword
+// arguments by name. It panics on a spread argument.
+func quoteArgs(exp *ast.CallExpression) ([]*object.Quote, map[string]*object.Quote) {
 	args := []*object.Quote{}
+	var keywords map[string]*object.Quote
 
 	for _, a := range exp.Arguments {
+		if _, ok := a.(*ast.SpreadElement); ok {
+			// the elements aren't known until the expansion is evaluated
+			panic("cannot spread arguments into a macro call")
+		}
+		if kw, ok := a.(*ast.KeywordArgument); ok {
+			if keywords == nil {
+				keywords = map[string]*object.Quote{}
+			}
+			keywords[kw.Name.Value] = &object.Quote{Node: kw.Value}
+			continue
+		}
 		args = append(args, &object.Quote{Node: a})
 	}
 
-	return args
+	return args, keywords
 }
 
+// extendMacroEnv binds the quoted arguments of a call to the parameters of
+// macro, by position, then by name. It panics on arguments that don't fit
+// the parameters, as the call can't be expanded.
 func extendMacroEnv(
 	macro *object.Macro,
 	args []*object.Quote,
+	keywords map[string]*object.Quote,
 ) *object.Environment {
 	extended := object.NewEnclosedEnvironment(macro.Env)
 
+	if len(args) > len(macro.Parameters) {
+		panic(fmt.Sprintf("wrong number of macro arguments. got=%d, want=%d",
+			len(args), len(macro.Parameters)))
+	}
+
+	params := map[string]bool{}
 	for paramIdx, param := range macro.Parameters {
-		extended.Set(param.Value, args[paramIdx])
+		params[param.Value] = true
+
+		keyword, hasKeyword := keywords[param.Value]
+		switch {
+		case paramIdx < len(args):
+			if hasKeyword {
+				panic(fmt.Sprintf("multiple values for macro argument %s", param.Value))
+			}
+			extended.Set(param.Value, args[paramIdx])
+		case hasKeyword:
+			extended.Set(param.Value, keyword)
+		default:
+			panic(fmt.Sprintf("missing macro argument for parameter %s", param.Value))
+		}
+	}
+
+	unknown := []string{}
+	for name := range keywords {
+		if !params[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		panic(fmt.Sprintf("unexpected macro keyword argument %s", unknown[0]))
 	}
 
 	return extended
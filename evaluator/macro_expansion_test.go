@@ -96,6 +96,70 @@ func TestExpandMacros(t *testing.T) {
 			`,
 			`if (!(10 > 5)) { print("not greater") } else { print("greater?!") }`,
 		},
+		{
+			`
+			let sign = macro(x, negative, zero, positive) {
+				quote(if (unquote(x) < 0) {
+					unquote(negative);
+				} else if (unquote(x) == 0) {
+					unquote(zero);
+				} else {
+					unquote(positive);
+				});
+			};
+
+			sign(n, "neg", "zero", "pos");
+			`,
+			`if (n < 0) { "neg" } else if (n == 0) { "zero" } else { "pos" }`,
+		},
+		{
+			`
+			let greet = macro(name) { quote("say \"" + unquote(name)); };
+
+			greet("tab\there");
+			`,
+			`"say \"" + "tab\there"`,
+		},
+		{
+			`
+			let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+
+			1 |> reverse(2);
+			`,
+			`2 - 1`,
+		},
+		{
+			`
+			let double = macro(x) { quote(unquote(x) * 2); };
+
+			f(g(double(2)));
+			`,
+			`f(g(2 * 2))`,
+		},
+		{
+			`
+			let prepend = macro(x, xs) { quote([unquote(x), ...unquote(xs)]); };
+
+			prepend(0, [1, 2]);
+			`,
+			`[0, ...[1, 2]]`,
+		},
+		{
+			`
+			let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+
+			reverse(b: 1, a: 2);
+			`,
+			`1 - 2`,
+		},
+		{
+			`
+			let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+
+			reverse(2, b: 1);
+			`,
+			`1 - 2`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -112,6 +176,79 @@ func TestExpandMacros(t *testing.T) {
 	}
 }
 
+func TestExpandMacrosArgumentErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`let m = macro(a) { a }; m(1, 2);`,
+			"wrong number of macro arguments. got=2, want=1",
+		},
+		{
+			`let m = macro(a, b) { a }; m(1);`,
+			"missing macro argument for parameter b",
+		},
+		{
+			`let m = macro(a) { a }; m(1, a: 2);`,
+			"multiple values for macro argument a",
+		},
+		{
+			`let m = macro(a) { a }; m(a: 1, c: 2);`,
+			"unexpected macro keyword argument c",
+		},
+		{
+			`let m = macro(a) { a }; m(...[1]);`,
+			"cannot spread arguments into a macro call",
+		},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+
+		func() {
+			defer func() {
+				if r := recover(); r != tt.expected {
+					t.Errorf("wrong panic for %q. want=%q, got=%v", tt.input, tt.expected, r)
+				}
+			}()
+			ExpandMacros(program, env)
+		}()
+	}
+}
+
+// TestEvalStrayArguments evaluates argument nodes on their own, as a macro
+// could place them.
+func TestEvalStrayArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(x: 1)", "keyword argument x outside a call"},
+		{"f(...xs)", "cannot spread outside a list, map or argument list"},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		arg := stmt.Expression.(*ast.CallExpression).Arguments[0]
+
+		evaluated := Eval(arg, object.NewEnvironment())
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q",
+				tt.input, tt.expected, errObj.Message)
+		}
+	}
+}
+
 func testParseProgram(input string) *ast.Program {
 	l := lexer.New(input)
 	p := parser.New(l)
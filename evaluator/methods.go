@@ -0,0 +1,67 @@
+package evaluator
+
+import (
+	"lemon/ast"
+	"lemon/object"
+)
+
+// methods are the builtins that can be called as methods of each type,
+// with the receiver as the first argument: `arr.push(4)` is `push(arr, 4)`.
+var methods = map[object.ObjectType]map[string]*object.Builtin{
+	object.ARRAY_OBJ: methodSet(
+		"len", "first", "last", "rest", "push", "pop", "clone",
+		"merge", "merged", "sort", "sorted", "str", "bool",
+	),
+	object.STRING_OBJ: methodSet(
+		"len", "first", "last", "rest", "clone", "merge", "merged",
+		"int", "float", "str", "bool",
+	),
+	object.MAP_OBJ:         methodSet("keys", "values", "clone", "merge", "merged", "str", "bool"),
+	object.INTEGER_OBJ:     methodSet("int", "float", "str", "bool"),
+	object.BIG_INTEGER_OBJ: methodSet("int", "float", "str", "bool"),
+	object.FLOAT_OBJ:       methodSet("int", "float", "str", "bool"),
+	object.BOOLEAN_OBJ:     methodSet("int", "float", "str", "bool"),
+}
+
+func methodSet(names ...string) map[string]*object.Builtin {
+	set := make(map[string]*object.Builtin, len(names))
+	for _, name := range names {
+		set[name] = builtins[name]
+	}
+	return set
+}
+
+// lookupMethod finds the function that obj.name(...) calls: a function
+// stored under the key name when obj is a map, or else the method of obj's
+// type. The receiver is returned when it has to be passed as the first
+// argument. Both are nil if there is no such method.
+func lookupMethod(obj object.Object, name string) (fn, receiver object.Object) {
+	if m, ok := obj.(*object.Map); ok {
+		key := &object.String{Value: name}
+		if pair, ok := m.Pairs[key.HashKey()]; ok {
+			return pair.Value, nil
+		}
+	}
+
+	if method, ok := methods[obj.Type()][name]; ok {
+		return method, obj
+	}
+
+	return nil, nil
+}
+
+func evalMemberExpression(me *ast.MemberExpression, env *object.Environment) object.Object {
+	obj := Eval(me.Object, env)
+	if isError(obj) {
+		return obj
+	}
+	if me.Optional && obj == NULL {
+		return NULL
+	}
+
+	if obj.Type() != object.MAP_OBJ {
+		return newError("field access not supported: %s", obj.Type())
+	}
+
+	return evalMapIndexExpression(obj, &object.String{Value: me.Property.Value})
+}
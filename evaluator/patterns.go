@@ -0,0 +1,155 @@
+package evaluator
+
+import (
+	"lemon/ast"
+	"lemon/object"
+)
+
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(me.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		// names bound by the pattern are only visible in its arm
+		armEnv := object.NewEnclosedEnvironment(env)
+
+		if !matchPattern(arm.Pattern, subject, armEnv) {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return Eval(arm.Body, armEnv)
+	}
+
+	return newError("non-exhaustive match: no arm matches %s", subject.Inspect())
+}
+
+// matchPattern reports whether value matches pattern, binding the names
+// in the pattern in env as it goes. On a mismatch env may hold some of
+// the bindings, so callers use a fresh environment for each attempt.
+func matchPattern(pattern ast.Pattern, value object.Object, env *object.Environment) bool {
+	return bindPattern(pattern, value, env) == nil
+}
+
+// bindPattern destructures value with pattern, binding the names in the
+// pattern in env, and returns an error explaining the first mismatch.
+func bindPattern(pattern ast.Pattern, value object.Object, env *object.Environment) *object.Error {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return nil
+
+	case *ast.Identifier:
+		env.Set(pattern.Value, value)
+		return nil
+
+	case *ast.LiteralPattern:
+		literal := Eval(pattern.Value, env)
+		if err, ok := literal.(*object.Error); ok {
+			return err
+		}
+		if !objectsEqual(literal, value) {
+			return newError("%s does not match pattern %s", value.Inspect(), pattern)
+		}
+		return nil
+
+	case *ast.ArrayPattern:
+		return bindArrayPattern(pattern, value, env)
+
+	case *ast.MapPattern:
+		return bindMapPattern(pattern, value, env)
+
+	default:
+		return newError("unknown pattern: %s", pattern)
+	}
+}
+
+func bindArrayPattern(pattern *ast.ArrayPattern, value object.Object, env *object.Environment) *object.Error {
+	array, ok := value.(*object.Array)
+	if !ok {
+		return newError("cannot destructure %s with array pattern %s", value.Type(), pattern)
+	}
+
+	elements := pattern.Elements
+	var rest *ast.RestPattern
+	if n := len(elements); n > 0 {
+		if rest, ok = elements[n-1].(*ast.RestPattern); ok {
+			elements = elements[:n-1]
+		}
+	}
+
+	if rest == nil && len(array.Elements) != len(elements) {
+		return newError("array pattern %s needs %d elements, got %d",
+			pattern, len(elements), len(array.Elements))
+	}
+	if len(array.Elements) < len(elements) {
+		return newError("array pattern %s needs at least %d elements, got %d",
+			pattern, len(elements), len(array.Elements))
+	}
+
+	for i, element := range elements {
+		if err := bindPattern(element, array.Elements[i], env); err != nil {
+			return err
+		}
+	}
+
+	if rest != nil && rest.Name != nil {
+		remaining := make([]object.Object, len(array.Elements)-len(elements))
+		copy(remaining, array.Elements[len(elements):])
+		env.Set(rest.Name.Value, &object.Array{Elements: remaining})
+	}
+
+	return nil
+}
+
+func bindMapPattern(pattern *ast.MapPattern, value object.Object, env *object.Environment) *object.Error {
+	m, ok := value.(*object.Map)
+	if !ok {
+		return newError("cannot destructure %s with map pattern %s", value.Type(), pattern)
+	}
+
+	for i, keyNode := range pattern.Keys {
+		keyObj := Eval(keyNode, env)
+		if err, ok := keyObj.(*object.Error); ok {
+			return err
+		}
+		key, ok := keyObj.(object.Hashable)
+		if !ok {
+			return newError("unusable as hashable key: %s", keyObj.Type())
+		}
+
+		pair, ok := m.Pairs[key.HashKey()]
+		if !ok {
+			return newError("key %s not found for map pattern %s", keyNode, pattern)
+		}
+
+		if err := bindPattern(pattern.Values[i], pair.Value, env); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// objectsEqual compares literal values: numbers by value, whatever their
+// type, strings by content, and everything else by identity.
+func objectsEqual(a, b object.Object) bool {
+	switch {
+	case isNumber(a) && isNumber(b):
+		return evalInfixExpression("==", a, b) == TRUE
+	case a.Type() == object.STRING_OBJ && b.Type() == object.STRING_OBJ:
+		return a.(*object.String).Value == b.(*object.String).Value
+	default:
+		return a == b
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"lemon/ast"
 	"lemon/object"
 	"lemon/token"
+	"sort"
 )
 
 func quote(node ast.Node, env *object.Environment) object.Object {
@@ -41,6 +42,20 @@ func convertObjectToASTNode(obj object.Object) ast.Node {
 		}
 		return &ast.IntegerLiteral{Token: t, Value: obj.Value}
 
+	case *object.BigInteger:
+		t := token.Token{
+			Type:    token.INT,
+			Literal: obj.Value.String(),
+		}
+		return &ast.BigIntegerLiteral{Token: t, Value: obj.Value}
+
+	case *object.Float:
+		t := token.Token{
+			Type:    token.FLOAT,
+			Literal: obj.Inspect(),
+		}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}
+
 	case *object.Boolean:
 		var t token.Token
 		if obj.Value {
@@ -71,12 +86,16 @@ func convertObjectToASTNode(obj object.Object) ast.Node {
 
 	case *object.Map:
 		pairs := make(map[ast.Expression]ast.Expression)
+		keys := []ast.Expression{}
 		for _, value := range obj.Pairs {
 			key := convertObjectToASTNode(value.Key).(ast.Expression)
 			val := convertObjectToASTNode(value.Value).(ast.Expression)
 			pairs[key] = val
+			keys = append(keys, key)
 		}
-		return &ast.MapLiteral{Token: token.Token{Type: token.LBRACE, Literal: "{"}, Pairs: pairs}
+		// maps are unordered, sort the keys so the literal reads the same every time
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		return &ast.MapLiteral{Token: token.Token{Type: token.LBRACE, Literal: "{"}, Pairs: pairs, Keys: keys}
 
 	case *object.Function:
 		return &ast.Identifier{
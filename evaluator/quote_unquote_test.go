@@ -75,6 +75,18 @@ func TestQuoteUnquote(t *testing.T) {
 			quote(unquote(foobar))`,
 			`8`,
 		},
+		{
+			`quote(unquote(99999999999999999999 + 1))`,
+			`100000000000000000000`,
+		},
+		{
+			`quote(if (a) { 1 } else if (unquote(1 < 2)) { unquote(2 + 2) } else { 3 })`,
+			`if (a) { 1 } else if (true) { 4 } else { 3 }`,
+		},
+		{
+			`quote(unquote(1.5 * 2))`,
+			`3.0`,
+		},
 		{
 			`quote(unquote(true))`,
 			`true`,
@@ -91,6 +103,26 @@ func TestQuoteUnquote(t *testing.T) {
 			`quote(unquote("foo" + "bar"))`,
 			`"foobar"`,
 		},
+		{
+			`quote(unquote("say \"hi\"\n"))`,
+			`"say \"hi\"\n"`,
+		},
+		{
+			`let xs = [1, 2]; quote([0, ...unquote(xs), f(...unquote(xs))])`,
+			`[0, ...[1, 2], f(...[1, 2])]`,
+		},
+		{
+			`quote({...unquote(1 + 1), "a": 1})`,
+			`{...2, "a": 1}`,
+		},
+		{
+			`let x = 4; quote("x is ${unquote(x + 1)}")`,
+			`"x is ${5}"`,
+		},
+		{
+			"quote(unquote(`C:\\temp`))",
+			`"C:\\temp"`,
+		},
 		{
 			`quote(unquote([1, 2, 3, 4]))`,
 			`[1, 2, 3, 4]`,
@@ -101,7 +133,7 @@ func TestQuoteUnquote(t *testing.T) {
 		},
 		{
 			`quote(unquote({"foo": 5, "bar": 8}))`,
-			`{"foo": 5, "bar": 8}`,
+			`{"bar": 8, "foo": 5}`,
 		},
 		{
 			`quote(unquote({"foo": 5, "bar": 8}["foo"]))`,
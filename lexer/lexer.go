@@ -1,24 +1,109 @@
 package lexer
 
-import "lemon/token"
+import (
+	"fmt"
+	"lemon/token"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
 type Lexer struct {
 	input        string
+	filename     string
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
-	ch           byte // current char under examination
+	ch           rune // current char under examination
+	line         int  // line of the current char
+	column       int  // column of the current char, counted in runes
+	trivia       bool // whether to attach whitespace and comments to tokens
+
+	// open ${...} interpolations, innermost last
+	interpolations []interpolation
+	// the string literal that continues after an INTERPOLATION_END
+	resume *interpolation
+
+	errors []Error
+}
+
+type interpolation struct {
+	braces    int            // unclosed '{' inside the embedded expression
+	multiline bool           // whether the enclosing string is triple-quoted
+	start     token.Position // opening quote of the enclosing string
+}
+
+// Error describes malformed input, such as an unterminated string or a
+// character that can't start a token. The lexer still returns a token
+// for it and goes on. Pos and End delimit the offending source.
+type Error struct {
+	Pos     token.Position
+	End     token.Position
+	Message string
+}
+
+func (e Error) Error() string {
+	return e.Pos.String() + ": " + e.Message
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewWithFilename("", input)
+}
+
+// NewWithFilename creates a lexer whose token positions refer to filename.
+func NewWithFilename(filename, input string) *Lexer {
+	l := &Lexer{input: input, filename: filename, line: 1}
 	l.readChar()
 	return l
 }
 
+// NewWithTrivia creates a lexer that keeps whitespace and comments as the
+// Leading trivia of the token that follows them, with trailing trivia on
+// the EOF token. Tools that need the exact source, like formatters and doc
+// generators, use it; the parser does not need it.
+func NewWithTrivia(filename, input string) *Lexer {
+	l := NewWithFilename(filename, input)
+	l.trivia = true
+	return l
+}
+
+// Errors returns the problems found in the input read so far.
+func (l *Lexer) Errors() []Error {
+	return l.errors
+}
+
+// errorAt reports an error about the source from pos to the end of the
+// current char.
+func (l *Lexer) errorAt(pos token.Position, format string, a ...interface{}) {
+	end := l.currentPosition()
+	if l.ch != 0 {
+		end.Offset = l.readPosition
+		end.Column++
+	}
+	l.errors = append(l.errors, Error{Pos: pos, End: end, Message: fmt.Sprintf(format, a...)})
+}
+
+// Source returns the text a token was read from, including quotes and
+// escapes as written.
+func (l *Lexer) Source(tok token.Token) string {
+	return l.input[tok.Pos.Offset:tok.End.Offset]
+}
+
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
-	l.skipWhitespace()
+	if open := l.resume; open != nil {
+		// the literal resumes right after the "}", whitespace included
+		l.resume = nil
+		pos := l.currentPosition()
+		tok = l.readString(open.start, open.multiline)
+		l.readChar()
+		return l.finish(tok, pos, nil)
+	}
+
+	leading := l.skipTrivia()
+
+	pos := l.currentPosition()
 
 	switch l.ch {
 	case '=':
@@ -27,13 +112,15 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar()
 			literal := string(ch) + string(l.ch)
 			tok = token.Token{Type: token.EQ, Literal: literal}
+		} else if l.peekChar() == '>' {
+			tok = l.newTwoCharToken(token.ARROW)
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		tok = l.newAssignToken(token.PLUS, token.PLUS_ASSIGN)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		tok = l.newAssignToken(token.MINUS, token.MINUS_ASSIGN)
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -44,28 +131,72 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		if l.peekChar() == '/' {
-			for l.ch != '\n' && l.ch != 0 {
-				l.readChar()
-			}
-			return l.NextToken()
-		} else if l.peekChar() == '*' {
+		tok = l.newAssignToken(token.SLASH, token.SLASH_ASSIGN)
+	case '*':
+		if l.peekChar() == '*' {
+			tok = l.newTwoCharToken(token.POWER)
+		} else {
+			tok = l.newAssignToken(token.ASTERISK, token.ASTERISK_ASSIGN)
+		}
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
+	case '<':
+		switch l.peekChar() {
+		case '=':
+			tok = l.newTwoCharToken(token.LT_EQ)
+		case '<':
+			tok = l.newTwoCharToken(token.SHIFT_LEFT)
+		default:
+			tok = newToken(token.LT, l.ch)
+		}
+	case '>':
+		switch l.peekChar() {
+		case '=':
+			tok = l.newTwoCharToken(token.GT_EQ)
+		case '>':
+			tok = l.newTwoCharToken(token.SHIFT_RIGHT)
+		default:
+			tok = newToken(token.GT, l.ch)
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			tok = l.newTwoCharToken(token.AND)
+		} else {
+			tok = newToken(token.BIT_AND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.newTwoCharToken(token.OR)
+		} else if l.peekChar() == '>' {
+			tok = l.newTwoCharToken(token.PIPE)
+		} else {
+			tok = newToken(token.BIT_OR, l.ch)
+		}
+	case '^':
+		tok = newToken(token.BIT_XOR, l.ch)
+	case '?':
+		if l.peekChar() == '?' {
+			tok = l.newTwoCharToken(token.NULLISH)
+		} else if l.peekChar() == '.' {
+			tok = l.newTwoCharToken(token.OPTIONAL_CHAIN)
+		} else {
+			l.errorAt(pos, "unexpected character %q", l.ch)
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
 			l.readChar()
 			l.readChar()
-			for l.ch != '*' && l.peekChar() != '/' && l.ch != 0 {
-				l.readChar()
-			}
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else if strings.HasPrefix(l.input[l.position:], "..=") {
 			l.readChar()
 			l.readChar()
-			return l.NextToken()
+			tok = token.Token{Type: token.RANGE_INCLUSIVE, Literal: "..="}
+		} else if l.peekChar() == '.' {
+			tok = l.newTwoCharToken(token.RANGE)
+		} else {
+			tok = newToken(token.DOT, l.ch)
 		}
-		tok = newToken(token.SLASH, l.ch)
-	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
-	case '<':
-		tok = newToken(token.LT, l.ch)
-	case '>':
-		tok = newToken(token.GT, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ':':
@@ -73,9 +204,25 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '{':
+		if n := len(l.interpolations); n > 0 {
+			l.interpolations[n-1].braces++
+		}
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
-		tok = newToken(token.RBRACE, l.ch)
+		n := len(l.interpolations)
+		if n > 0 && l.interpolations[n-1].braces == 0 {
+			// end of an embedded expression, the string literal resumes
+			// with the next token
+			open := l.interpolations[n-1]
+			l.interpolations = l.interpolations[:n-1]
+			l.resume = &open
+			tok = newToken(token.INTERPOLATION_END, l.ch)
+		} else {
+			if n > 0 {
+				l.interpolations[n-1].braces--
+			}
+			tok = newToken(token.RBRACE, l.ch)
+		}
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -85,88 +232,347 @@ func (l *Lexer) NextToken() token.Token {
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
 	case '"':
+		multiline := strings.HasPrefix(l.input[l.position:], `"""`)
+		if multiline {
+			l.skipMultilineStringStart()
+		}
+		l.readChar()
+		tok = l.readString(pos, multiline)
+	case '`':
 		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok.Literal = l.readRawString(pos)
 	case 0:
+		if len(l.interpolations) > 0 {
+			l.errorAt(l.interpolations[0].start, "unterminated string literal")
+			l.interpolations = nil
+		}
 		tok.Literal = ""
 		tok.Type = token.EOF
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
-			return tok
+			return l.finish(tok, pos, leading)
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
-			return tok
+			tok.Type, tok.Literal = l.readNumber()
+			return l.finish(tok, pos, leading)
 		} else {
+			l.errorAt(pos, "unexpected character %q", l.ch)
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	}
 
 	l.readChar()
+	return l.finish(tok, pos, leading)
+}
+
+// finish sets the range and the leading trivia of a token that ends just
+// before the current char.
+func (l *Lexer) finish(tok token.Token, pos token.Position, leading []token.Trivia) token.Token {
+	tok.Pos = pos
+	tok.End = l.currentPosition()
+	tok.Leading = leading
 	return tok
 }
 
-func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
-		l.readChar()
+// skipTrivia skips whitespace and comments. In trivia mode it returns
+// them, otherwise it returns nil.
+func (l *Lexer) skipTrivia() []token.Trivia {
+	var trivia []token.Trivia
+	for {
+		pos := l.currentPosition()
+		var kind token.TriviaKind
+
+		switch {
+		case isWhitespace(l.ch):
+			kind = token.WHITESPACE
+			for isWhitespace(l.ch) {
+				l.readChar()
+			}
+		case l.ch == '/' && l.peekChar() == '/':
+			kind = token.LINE_COMMENT
+			for l.ch != '\n' && l.ch != 0 {
+				l.readChar()
+			}
+		case l.ch == '/' && l.peekChar() == '*':
+			kind = token.BLOCK_COMMENT
+			l.readChar()
+			l.readChar()
+			for l.ch != 0 && !(l.ch == '*' && l.peekChar() == '/') {
+				l.readChar()
+			}
+			if l.ch == 0 {
+				l.errorAt(pos, "unterminated block comment")
+			}
+			l.readChar()
+			l.readChar()
+		default:
+			return trivia
+		}
+
+		if l.trivia {
+			text := l.input[pos.Offset:l.position]
+			trivia = append(trivia, token.Trivia{Kind: kind, Text: text, Pos: pos})
+		}
 	}
 }
 
 func (l *Lexer) readChar() {
+	if l.readPosition > len(l.input) {
+		// already past the end, stay on EOF
+		return
+	}
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	size := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, size = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += size
+	l.column += 1
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
+
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	} else {
-		return l.input[l.readPosition]
+		ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		return ch
 	}
 }
 
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || unicode.IsDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer or a float literal such as 1_000, 0xFF,
+// 1.5, 2e10 or 6.02e-23. A '.' is only part of the number when a digit
+// follows it. Digits are read generously, the parser validates them.
+func (l *Lexer) readNumber() (token.TokenType, string) {
+	tokenType := token.TokenType(token.INT)
 	position := l.position
-	for isDigit(l.ch) {
+
+	if l.ch == '0' && strings.ContainsRune("xXoObB", l.peekChar()) {
 		l.readChar()
+		l.readChar()
+		for isLetter(l.ch) || isDigit(l.ch) {
+			l.readChar()
+		}
+		return tokenType, l.input[position:l.position]
 	}
-	return l.input[position:l.position]
+
+	l.readDigits()
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+
+	if (l.ch == 'e' || l.ch == 'E') && l.isExponentStart() {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+
+	return tokenType, l.input[position:l.position]
 }
 
-func (l *Lexer) readString() string {
+// readDigits reads decimal digits and '_' separators.
+func (l *Lexer) readDigits() {
+	for isDigit(l.ch) || l.ch == '_' {
+		l.readChar()
+	}
+}
+
+// isExponentStart reports whether the 'e' under examination starts an
+// exponent, i.e. is followed by digits with an optional sign.
+func (l *Lexer) isExponentStart() bool {
+	rest := l.input[l.readPosition:]
+	if len(rest) > 0 && (rest[0] == '+' || rest[0] == '-') {
+		rest = rest[1:]
+	}
+	return len(rest) > 0 && isDigit(rune(rest[0]))
+}
+
+// readString reads the body of a string literal, starting at the current
+// char. It stops on the closing quote, returning a STRING token,
+// or on the "{" of an embedded "${", returning an INTERPOLATION token.
+// start is the opening quote of the literal.
+func (l *Lexer) readString(start token.Position, multiline bool) token.Token {
+	var out strings.Builder
+	for ; ; l.readChar() {
+		if l.ch == 0 {
+			l.errorAt(start, "unterminated string literal")
+			break
+		}
+		if multiline && strings.HasPrefix(l.input[l.position:], `"""`) {
+			// leave the last closing quote for NextToken to consume
+			l.readChar()
+			l.readChar()
+			break
+		}
+		if !multiline && l.ch == '"' {
+			break
+		}
+		if l.ch == '$' && l.peekChar() == '{' {
+			rest := strings.TrimLeft(l.input[l.readPosition+1:], " \t\r\n")
+			if strings.HasPrefix(rest, "}") {
+				// skip it, so the rest of the literal still reads as one string
+				pos := l.currentPosition()
+				for l.ch != '}' {
+					l.readChar()
+				}
+				l.errorAt(pos, "empty interpolation")
+				continue
+			}
+			l.readChar()
+			l.interpolations = append(l.interpolations, interpolation{multiline: multiline, start: start})
+			return token.Token{Type: token.INTERPOLATION, Literal: out.String()}
+		}
+		if l.ch == '\\' {
+			l.readEscape(&out)
+			continue
+		}
+		out.WriteRune(l.ch)
+	}
+	return token.Token{Type: token.STRING, Literal: out.String()}
+}
+
+// skipMultilineStringStart moves to the last opening quote of a
+// """triple-quoted""" string, dropping a newline directly after it.
+func (l *Lexer) skipMultilineStringStart() {
+	l.readChar()
+	l.readChar()
+	if l.peekChar() == '\r' && strings.HasPrefix(l.input[l.readPosition:], "\r\n") {
+		l.readChar()
+	}
+	if l.peekChar() == '\n' {
+		l.readChar()
+	}
+}
+
+// readRawString reads a `raw` string, which keeps backslashes as-is.
+func (l *Lexer) readRawString(start token.Position) string {
 	position := l.position + 1
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
+		if l.ch == 0 {
+			l.errorAt(start, "unterminated raw string literal")
+			break
+		}
+		if l.ch == '`' {
 			break
 		}
 	}
 	return l.input[position:l.position]
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// readEscape decodes the escape sequence starting at the current
+// backslash and leaves the lexer on its last character.
+func (l *Lexer) readEscape(out *strings.Builder) {
+	pos := l.currentPosition()
+	l.readChar()
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case '0':
+		out.WriteByte(0)
+	case '\\', '"', '\'', '`', '$':
+		out.WriteRune(l.ch)
+	case 'u':
+		if l.peekChar() != '{' {
+			l.errorAt(pos, `invalid unicode escape: expected "{" after \u`)
+			out.WriteString(`\u`)
+			return
+		}
+		l.readChar()
+		start := l.readPosition
+		for !strings.ContainsRune("}\"\n", l.peekChar()) && l.peekChar() != 0 {
+			l.readChar()
+		}
+		digits := l.input[start:l.readPosition]
+		closed := l.peekChar() == '}'
+		if closed {
+			l.readChar()
+		}
+		// the sequence as written, with the closing brace if there is one
+		sequence := l.input[pos.Offset:l.readPosition]
+		code, err := strconv.ParseUint(digits, 16, 32)
+		if !closed || err != nil || !utf8.ValidRune(rune(code)) {
+			l.errorAt(pos, `invalid unicode escape "%s"`, sequence)
+			out.WriteString(sequence)
+			return
+		}
+		out.WriteRune(rune(code))
+	case 0:
+		out.WriteByte('\\')
+	default:
+		// unknown escapes are kept as written
+		l.errorAt(pos, "unknown escape sequence %q", `\`+string(l.ch))
+		out.WriteByte('\\')
+		out.WriteRune(l.ch)
+	}
+}
+
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
+}
+
+func isWhitespace(ch rune) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
+
+// newTwoCharToken returns an operator spelled with the current and the
+// next char, like "<=", whose token type is its spelling.
+func (l *Lexer) newTwoCharToken(tokenType token.TokenType) token.Token {
+	l.readChar()
+	return token.Token{Type: tokenType, Literal: string(tokenType)}
+}
+
+// newAssignToken returns the compound assignment form of an operator,
+// like "+=", when the current char is followed by '='.
+func (l *Lexer) newAssignToken(operator, assign token.TokenType) token.Token {
+	if l.peekChar() == '=' {
+		return l.newTwoCharToken(assign)
+	}
+	return newToken(operator, l.ch)
+}
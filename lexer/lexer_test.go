@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"strings"
 	"testing"
 
 	"lemon/token"
@@ -31,6 +32,15 @@ if (5 < 10) {
 [1, 2];
 {"foo": "bar"}
 macro(x, y) { x + y; };
+x += 1; x -= 2; x *= 3; x /= 4;
+a && b || c;
+a <= b >= c % d ** e & f | g ^ h << i >> j;
+match (x) { [_, ...rest] => rest }
+switch (x) { case 1: fallthrough; default: }
+x |> f;
+0..n 1..=2 [1:];
+a?.[0] ?? f?.();
+m.k?.f;
 `
 
 	tests := []struct {
@@ -136,7 +146,333 @@ macro(x, y) { x + y; };
 		{token.SEMICOLON, ";"},
 		{token.RBRACE, "}"},
 		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "4"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "d"},
+		{token.POWER, "**"},
+		{token.IDENT, "e"},
+		{token.BIT_AND, "&"},
+		{token.IDENT, "f"},
+		{token.BIT_OR, "|"},
+		{token.IDENT, "g"},
+		{token.BIT_XOR, "^"},
+		{token.IDENT, "h"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.IDENT, "i"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.IDENT, "j"},
+		{token.SEMICOLON, ";"},
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "_"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.RBRACKET, "]"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "rest"},
+		{token.RBRACE, "}"},
+		{token.SWITCH, "switch"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "case"},
+		{token.INT, "1"},
+		{token.COLON, ":"},
+		{token.FALLTHROUGH, "fallthrough"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "default"},
+		{token.COLON, ":"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "x"},
+		{token.PIPE, "|>"},
+		{token.IDENT, "f"},
+		{token.SEMICOLON, ";"},
+		{token.INT, "0"},
+		{token.RANGE, ".."},
+		{token.IDENT, "n"},
+		{token.INT, "1"},
+		{token.RANGE_INCLUSIVE, "..="},
+		{token.INT, "2"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COLON, ":"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.OPTIONAL_CHAIN, "?."},
+		{token.LBRACKET, "["},
+		{token.INT, "0"},
+		{token.RBRACKET, "]"},
+		{token.NULLISH, "??"},
+		{token.IDENT, "f"},
+		{token.OPTIONAL_CHAIN, "?."},
+		{token.LPAREN, "("},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "m"},
+		{token.DOT, "."},
+		{token.IDENT, "k"},
+		{token.OPTIONAL_CHAIN, "?."},
+		{token.IDENT, "f"},
+		{token.SEMICOLON, ";"},
+
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  // comment
+  x + "ab";`
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENT, 3, 3},
+		{token.PLUS, 3, 5},
+		{token.STRING, 3, 7},
+		{token.SEMICOLON, 3, 11},
+		{token.EOF, 3, 12},
+	}
+
+	l := NewWithFilename("test.mm", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedColumn, tok.Pos.Line, tok.Pos.Column)
+		}
+
+		if tok.Pos.Filename != "test.mm" {
+			t.Fatalf("tests[%d] - filename wrong. expected=%q, got=%q",
+				i, "test.mm", tok.Pos.Filename)
+		}
+	}
+}
+
+func TestUnicodeInput(t *testing.T) {
+	input := `let größe = "日本語";
+let π2 = größe;`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "größe", 5},
+		{token.ASSIGN, "=", 11},
+		{token.STRING, "日本語", 13},
+		{token.SEMICOLON, ";", 18},
+		{token.LET, "let", 1},
+		{token.IDENT, "π2", 5},
+		{token.ASSIGN, "=", 8},
+		{token.IDENT, "größe", 10},
+		{token.SEMICOLON, ";", 15},
+		{token.EOF, "", 16},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - column wrong. expected=%d, got=%d",
+				i, tt.expectedColumn, tok.Pos.Column)
+		}
+	}
+}
 
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+	}{
+		{`"plain"`, "plain"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"a\nb\tc\r"`, "a\nb\tc\r"},
+		{`"back\\slash"`, `back\slash`},
+		{`"\u{48}\u{e9}\u{1F34B}"`, "Hé🍋"},
+		{`"\q"`, `\q`},
+		{"`raw \\n \"string\"`", `raw \n "string"`},
+		{"`multi\nline`", "multi\nline"},
+		{"\"\"\"\nfirst\n  \"second\"\\t\n\"\"\"", "first\n  \"second\"\t\n"},
+		{`""""""`, ""},
+		{`""`, ""},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != token.STRING {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, token.STRING, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Fatalf("tests[%d] - expected EOF after string, got=%q (%q)",
+				i, next.Type, next.Literal)
+		}
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	input := `"total: ${price * qty} (${ {"a": "}"}["a"] })" "\${x}" """${"nested ${1}"}
+"""`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INTERPOLATION, "total: "},
+		{token.IDENT, "price"},
+		{token.ASTERISK, "*"},
+		{token.IDENT, "qty"},
+		{token.INTERPOLATION_END, "}"},
+		{token.INTERPOLATION, " ("},
+		{token.LBRACE, "{"},
+		{token.STRING, "a"},
+		{token.COLON, ":"},
+		{token.STRING, "}"},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.STRING, "a"},
+		{token.RBRACKET, "]"},
+		{token.INTERPOLATION_END, "}"},
+		{token.STRING, ")"},
+		{token.STRING, "${x}"},
+		{token.INTERPOLATION, ""},
+		{token.INTERPOLATION, "nested "},
+		{token.INT, "1"},
+		{token.INTERPOLATION_END, "}"},
+		{token.STRING, ""},
+		{token.INTERPOLATION_END, "}"},
+		{token.STRING, "\n"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	input := `5 3.14 0.5 1e3 2.5E-3 6e+2 7.x 8e 9. 0xFF 0o755 0b1010 1_000_000 1_000.5 0b102`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "5"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "0.5"},
+		{token.FLOAT, "1e3"},
+		{token.FLOAT, "2.5E-3"},
+		{token.FLOAT, "6e+2"},
+		{token.INT, "7"},
+		{token.DOT, "."},
+		{token.IDENT, "x"},
+		{token.INT, "8"},
+		{token.IDENT, "e"},
+		{token.INT, "9"},
+		{token.DOT, "."},
+		{token.INT, "0xFF"},
+		{token.INT, "0o755"},
+		{token.INT, "0b1010"},
+		{token.INT, "1_000_000"},
+		{token.FLOAT, "1_000.5"},
+		{token.INT, "0b102"},
 		{token.EOF, ""},
 	}
 
@@ -156,3 +492,149 @@ macro(x, y) { x + y; };
 		}
 	}
 }
+
+func TestTrivia(t *testing.T) {
+	input := "// adds two numbers\nlet add = /* inline */ fn(x, y) { x + y };\n// end"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLeading []token.Trivia
+	}{
+		{token.LET, []token.Trivia{
+			{Kind: token.LINE_COMMENT, Text: "// adds two numbers"},
+			{Kind: token.WHITESPACE, Text: "\n"},
+		}},
+		{token.IDENT, []token.Trivia{{Kind: token.WHITESPACE, Text: " "}}},
+		{token.ASSIGN, []token.Trivia{{Kind: token.WHITESPACE, Text: " "}}},
+		{token.FUNCTION, []token.Trivia{
+			{Kind: token.WHITESPACE, Text: " "},
+			{Kind: token.BLOCK_COMMENT, Text: "/* inline */"},
+			{Kind: token.WHITESPACE, Text: " "},
+		}},
+		{token.LPAREN, nil},
+	}
+
+	l := NewWithTrivia("", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if len(tok.Leading) != len(tt.expectedLeading) {
+			t.Fatalf("tests[%d] - wrong number of trivia. expected=%d, got=%d (%+v)",
+				i, len(tt.expectedLeading), len(tok.Leading), tok.Leading)
+		}
+
+		for j, trivia := range tt.expectedLeading {
+			if tok.Leading[j].Kind != trivia.Kind || tok.Leading[j].Text != trivia.Text {
+				t.Errorf("tests[%d] - trivia[%d] wrong. expected=%s %q, got=%s %q",
+					i, j, trivia.Kind, trivia.Text, tok.Leading[j].Kind, tok.Leading[j].Text)
+			}
+		}
+	}
+
+	for tok := l.NextToken(); ; tok = l.NextToken() {
+		if tok.Type == token.EOF {
+			if len(tok.Leading) != 2 || tok.Leading[1].Text != "// end" {
+				t.Errorf("EOF trivia wrong. got=%+v", tok.Leading)
+			}
+			break
+		}
+	}
+}
+
+func TestTriviaIsOptional(t *testing.T) {
+	l := New("// comment\nlet /* x */ x")
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Leading != nil {
+			t.Errorf("token %q has trivia without trivia mode: %+v", tok.Literal, tok.Leading)
+		}
+	}
+}
+
+func TestLosslessTokenStream(t *testing.T) {
+	inputs := []string{
+		"let x = 5; // five\n",
+		"/* a */ /* b * c */ fn(a, b) {\r\n\ta / b\n}",
+		"let s = \"tab\\t ${name} and ${ {\"k\": 1}[\"k\"] }\";",
+		"\"\"\"\nmulti ${x}\nline\"\"\" `raw\\n` 0x_FF 1.5e3",
+		"let 名前 = \"🍋\"; @ ",
+		"",
+		"   \n",
+	}
+
+	for _, input := range inputs {
+		l := NewWithTrivia("", input)
+
+		var out strings.Builder
+		for {
+			tok := l.NextToken()
+			for _, trivia := range tok.Leading {
+				out.WriteString(trivia.Text)
+			}
+			out.WriteString(l.Source(tok))
+			if tok.Type == token.EOF {
+				break
+			}
+		}
+
+		if out.String() != input {
+			t.Errorf("token stream is not lossless. expected=%q, got=%q", input, out.String())
+		}
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+	}{
+		{`let s = "abc`, []string{"1:9: unterminated string literal"}},
+		{"\"\"\"\nabc\"\"", []string{"1:1: unterminated string literal"}},
+		{"x = `raw", []string{"1:5: unterminated raw string literal"}},
+		{`"a ${b`, []string{"1:1: unterminated string literal"}},
+		{`"a ${b} c`, []string{"1:1: unterminated string literal"}},
+		{`"a${}b" "${ }${}"`, []string{"1:3: empty interpolation", "1:10: empty interpolation", "1:14: empty interpolation"}},
+		{"\"\"\"a${\n}\"\"\"", []string{"1:5: empty interpolation"}},
+		{"1 /* never\nclosed", []string{"1:3: unterminated block comment"}},
+		{"/* a * b / c */ 1", nil},
+		{"a @ b #", []string{`1:3: unexpected character '@'`, `1:7: unexpected character '#'`}},
+		{`"\q \u{110000} \u41"`, []string{
+			`1:2: unknown escape sequence "\\q"`,
+			`1:5: invalid unicode escape "\u{110000}"`,
+			`1:16: invalid unicode escape: expected "{" after \u`,
+		}},
+		{`"\u{zz} \u{} \u{41"`, []string{
+			`1:2: invalid unicode escape "\u{zz}"`,
+			`1:9: invalid unicode escape "\u{}"`,
+			`1:14: invalid unicode escape "\u{41"`,
+		}},
+		{"\"\\u{1F34B\n\"", []string{`1:2: invalid unicode escape "\u{1F34B"`}},
+		{`"ok \n \u{41}" ` + "`\\q`", nil},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+
+		errors := l.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q. expected=%d, got=%d (%v)",
+				tt.input, len(tt.expectedErrors), len(errors), errors)
+			continue
+		}
+
+		for i, expected := range tt.expectedErrors {
+			if errors[i].Error() != expected {
+				t.Errorf("errors[%d] wrong for %q. expected=%q, got=%q",
+					i, tt.input, expected, errors[i].Error())
+			}
+		}
+	}
+}
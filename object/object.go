@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"hash/fnv"
 	"lemon/ast"
+	"lemon/token"
+	"math"
+	"math/big"
+	"strconv"
 	"strings"
 )
 
@@ -12,14 +16,19 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	BIG_INTEGER_OBJ  = "BIG_INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
+	RANGE_OBJ        = "RANGE"
 	MAP_OBJ          = "MAP"
 	QUOTE_OBJ        = "QUOTE"
 	MACRO_OBJ        = "MACRO"
@@ -44,6 +53,40 @@ func (i *Integer) Type() ObjectType {
 	return INTEGER_OBJ
 }
 
+// BigInteger holds integers that do not fit in an Integer. Arithmetic
+// on Integers is promoted to it on overflow, and results that fit again
+// are turned back into Integers, so a BigInteger is never in int64 range.
+type BigInteger struct {
+	Value *big.Int
+}
+
+func (bi *BigInteger) Inspect() string {
+	return bi.Value.String()
+}
+
+func (bi *BigInteger) Type() ObjectType {
+	return BIG_INTEGER_OBJ
+}
+
+// Float
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	// keep floats recognizable: 2.0, not 2
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
 // bool
 type Boolean struct {
 	Value bool
@@ -75,15 +118,33 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// Break and Continue are signals a loop body returns to its loop, passed
+// up through blocks like a ReturnValue.
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
 type Error struct {
 	Message string
+	Pos     token.Position // where the error was raised, if known
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "\x1b[48;5;196mERROR:\x1b[0m " + e.Message }
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "\x1b[48;5;196mERROR:\x1b[0m " + e.Pos.String() + ": " + e.Message
+	}
+	return "\x1b[48;5;196mERROR:\x1b[0m " + e.Message
+}
 
 type Function struct {
-	Parameters []*ast.Identifier
+	Parameters []ast.Pattern
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -144,6 +205,23 @@ func (ao *Array) Inspect() string {
 	return out.String()
 }
 
+// Range is the integers from Start up to End, including End if Inclusive.
+// Its elements are only produced when it is iterated over.
+type Range struct {
+	Start     int64
+	End       int64
+	Inclusive bool
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	op := ".."
+	if r.Inclusive {
+		op = "..="
+	}
+	return strconv.FormatInt(r.Start, 10) + op + strconv.FormatInt(r.End, 10)
+}
+
 // Hashable types
 
 type Hashable interface {
@@ -171,6 +249,28 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// Floats that hold a whole number hash like the equal Integer, so that
+// 1.0 and 1 select the same map entry, just as 1.0 == 1.
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && !math.IsInf(f.Value, 0) {
+		if f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+			return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(f.Value))}
+		}
+		value, _ := new(big.Float).SetFloat64(f.Value).Int(nil)
+		return (&BigInteger{Value: value}).HashKey()
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
+func (bi *BigInteger) HashKey() HashKey {
+	h := fnv.New64a()
+	if bi.Value.Sign() < 0 {
+		h.Write([]byte{'-'})
+	}
+	h.Write(bi.Value.Bytes())
+	return HashKey{Type: bi.Type(), Value: h.Sum64()}
+}
+
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
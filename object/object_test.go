@@ -1,6 +1,9 @@
 package object
 
-import "testing"
+import (
+	"math/big"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -19,3 +22,37 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with same content have same hash keys")
 	}
 }
+
+func TestFloatHashKey(t *testing.T) {
+	one := &Integer{Value: 1}
+	oneFloat := &Float{Value: 1.0}
+	half1 := &Float{Value: 0.5}
+	half2 := &Float{Value: 0.5}
+
+	if one.HashKey() != oneFloat.HashKey() {
+		t.Errorf("whole floats have different hash keys than equal integers")
+	}
+	if half1.HashKey() != half2.HashKey() {
+		t.Errorf("floats with same value have different hash keys")
+	}
+	if half1.HashKey() == oneFloat.HashKey() {
+		t.Errorf("floats with different values have same hash keys")
+	}
+}
+
+func TestBigIntegerHashKey(t *testing.T) {
+	value1, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	value2, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	negative := new(big.Int).Neg(value1)
+
+	big1 := &BigInteger{Value: value1}
+	big2 := &BigInteger{Value: value2}
+	bigNegative := &BigInteger{Value: negative}
+
+	if big1.HashKey() != big2.HashKey() {
+		t.Errorf("big integers with same value have different hash keys")
+	}
+	if big1.HashKey() == bigNegative.HashKey() {
+		t.Errorf("big integers with different signs have same hash keys")
+	}
+}
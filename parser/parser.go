@@ -1,36 +1,72 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"lemon/ast"
 	"lemon/lexer"
 	"lemon/token"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // x = y or x += y
+	PIPE        // x |> f(y)
+	NULLISH     // x ?? y
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
+	BIT_OR      // |
+	BIT_XOR     // ^
+	BIT_AND     // &
 	EQUALS      // ==
 	LESSGREATER // > or <
+	RANGE       // a..b or a..=b
+	SHIFT       // << or >>
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
+	POWER       // X ** Y, so -X ** Y is -(X ** Y)
 	CALL        // myFunction(X)
 	INDEX       // array[index]
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.PIPE:            PIPE,
+	token.NULLISH:         NULLISH,
+	token.OR:              LOGICAL_OR,
+	token.AND:             LOGICAL_AND,
+	token.BIT_OR:          BIT_OR,
+	token.BIT_XOR:         BIT_XOR,
+	token.BIT_AND:         BIT_AND,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.LT_EQ:           LESSGREATER,
+	token.GT_EQ:           LESSGREATER,
+	token.RANGE:           RANGE,
+	token.RANGE_INCLUSIVE: RANGE,
+	token.SHIFT_LEFT:      SHIFT,
+	token.SHIFT_RIGHT:     SHIFT,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.POWER:           POWER,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
+	token.OPTIONAL_CHAIN:  INDEX,
+	token.DOT:             INDEX,
 }
 
 type (
@@ -38,9 +74,27 @@ type (
 	infixParseFn  func(ast.Expression) ast.Expression
 )
 
+// blockKind is a kind of block that break and continue care about.
+type blockKind int
+
+const (
+	loopBlock   blockKind = iota
+	switchBlock           // left by break but not by continue
+	valueBlock            // the body of an if expression whose value is used
+)
+
 type Parser struct {
-	l      *lexer.Lexer
-	errors []string
+	l           *lexer.Lexer
+	diagnostics []Diagnostic
+	panicking   bool // skipping tokens until the next statement after an error
+
+	lexerErrors    int              // lexer errors already copied into errors
+	blocks         []blockKind      // blocks around the current token, within the function
+	interpolations []token.Position // "${" of the interpolations around the current token
+	ifStatement    bool             // the next if expression is a whole statement
+	braces         int              // '{' read and not closed yet
+	blockBraces    int              // braces just inside the innermost block
+	brackets       int              // '(' and '[' read and not closed yet
 
 	curToken  token.Token
 	peekToken token.Token
@@ -51,14 +105,16 @@ type Parser struct {
 
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
-		l:      l,
-		errors: []string{},
+		l:           l,
+		diagnostics: []Diagnostic{},
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.INTERPOLATION, p.parseInterpolatedString)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
@@ -67,6 +123,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseMapLiteral)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
 
@@ -75,13 +132,35 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.NULLISH, p.parseInfixExpression)
+	p.registerInfix(token.OPTIONAL_CHAIN, p.parseOptionalChain)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
 
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.PIPE, p.parsePipeExpression)
+	p.registerInfix(token.RANGE, p.parseRangeExpression)
+	p.registerInfix(token.RANGE_INCLUSIVE, p.parseRangeExpression)
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -93,6 +172,27 @@ func New(l *lexer.Lexer) *Parser {
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.braces++
+	case token.RBRACE:
+		p.braces--
+	case token.LPAREN, token.LBRACKET:
+		p.brackets++
+	case token.RPAREN, token.RBRACKET:
+		p.brackets--
+	}
+
+	// keep lexer errors in source order with our own
+	for _, err := range p.l.Errors()[p.lexerErrors:] {
+		p.diagnostics = append(p.diagnostics, Diagnostic{
+			Pos:     err.Pos,
+			End:     err.End,
+			Message: err.Message,
+		})
+	}
+	p.lexerErrors = len(p.l.Errors())
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -113,19 +213,164 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
+// Diagnostic is a problem found in the input, by the lexer or the parser.
+// Pos and End delimit the offending source. Expected and Found are set
+// when a specific token was missing.
+type Diagnostic struct {
+	Pos      token.Position
+	End      token.Position
+	Message  string
+	Expected token.TokenType
+	Found    token.TokenType
+}
+
+func (d Diagnostic) String() string {
+	return d.Pos.String() + ": " + d.Message
+}
+
+// Diagnostics returns the problems found so far, in the order they were
+// found, which is roughly source order.
+func (p *Parser) Diagnostics() []Diagnostic {
+	return p.diagnostics
+}
+
+// Errors returns the diagnostics as messages prefixed with their position.
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := make([]string, len(p.diagnostics))
+	for i, d := range p.diagnostics {
+		errors[i] = d.String()
+	}
+	return errors
+}
+
+// report records a parser error and starts skipping the rest of the
+// statement. Errors up to the next statement are likely caused by the
+// first one, so they are dropped.
+func (p *Parser) report(d Diagnostic) {
+	if p.panicking {
+		return
+	}
+	p.panicking = true
+	p.diagnostics = append(p.diagnostics, d)
+}
+
+// errorAt reports an error about tok.
+func (p *Parser) errorAt(tok token.Token, format string, a ...interface{}) {
+	p.report(Diagnostic{
+		Pos:     tok.Pos,
+		End:     tok.End,
+		Message: fmt.Sprintf(format, a...),
+	})
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.report(Diagnostic{
+		Pos: p.peekToken.Pos,
+		End: p.peekToken.End,
+		Message: fmt.Sprintf("expected next token to be %s, got %s instead",
+			t, p.peekToken.Type),
+		Expected: t,
+		Found:    p.peekToken.Type,
+	})
+}
+
+// incompleteInterpolationError reports an embedded expression that ends
+// before its "}", or doesn't end there, at the "${" that opened it.
+func (p *Parser) incompleteInterpolationError(open token.Position) {
+	end := open
+	end.Offset += 2
+	end.Column += 2
+	p.report(Diagnostic{
+		Pos:     open,
+		End:     end,
+		Message: "incomplete expression in interpolation",
+	})
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	if t == token.ILLEGAL {
+		// the lexer has reported it already
+		p.panicking = true
+		return
+	}
+	if n := len(p.interpolations); t == token.INTERPOLATION_END && n > 0 {
+		p.incompleteInterpolationError(p.interpolations[n-1])
+		return
+	}
+	p.report(Diagnostic{
+		Pos:     p.curToken.Pos,
+		End:     p.curToken.End,
+		Message: fmt.Sprintf("no prefix parse function for %s found", t),
+		Found:   t,
+	})
+}
+
+// synchronize skips the rest of a broken statement. It stops on the ';'
+// ending it, or before the '}' closing the enclosing block or a keyword
+// starting the next statement, so the caller's next nextToken resumes
+// parsing there. If the error was on the '}' closing the block, it stays
+// there, and the block ends. brackets is the number of brackets open
+// where the statement started: a keyword inside a bracket the statement
+// opened, as in `[x for x in xs if a if b]`, is still part of it.
+func (p *Parser) synchronize(brackets int) {
+	depth := 0 // blocks opened while skipping
+
+	for !p.peekTokenIs(token.EOF) {
+		if p.curTokenIs(token.RBRACE) && p.braces < p.blockBraces {
+			break
+		}
+		if depth == 0 {
+			if p.curTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) {
+				break
+			}
+			// a block isn't followed by a keyword inside a bracket, so the
+			// statement left that bracket unclosed
+			if (p.brackets <= brackets || p.curTokenIs(token.RBRACE)) &&
+				(statementKeywords[p.peekToken.Type] || p.peekStartsClause()) {
+				break
+			}
+		}
+
+		switch p.peekToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+		}
+
+		p.nextToken()
+	}
+
+	p.panicking = false
+}
+
+// partialStatement keeps what can be trusted of a statement that had an
+// error: the name of a let, but no expression, which may have missing
+// parts. Tools working on the partial AST can then print and walk it.
+func partialStatement(stmt ast.Statement) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		stmt.Value = nil
+		return stmt
+	case *ast.ReturnStatement:
+		stmt.ReturnValue = nil
+		return stmt
+	default:
+		return nil
+	}
+}
+
+// statementKeywords start a statement, which makes them good places to
+// resume parsing after an error.
+var statementKeywords = map[token.TokenType]bool{
+	token.SWITCH:   true,
+	token.LET:      true,
+	token.RETURN:   true,
+	token.IF:       true,
+	token.WHILE:    true,
+	token.FOR:      true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -133,7 +378,12 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for !p.curTokenIs(token.EOF) {
+		brackets := p.brackets
 		stmt := p.parseStatement()
+		if p.panicking {
+			stmt = partialStatement(stmt)
+			p.synchronize(brackets)
+		}
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
@@ -149,20 +399,32 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
+	case token.SWITCH:
+		return p.parseSwitchStatement()
+	case token.FALLTHROUGH:
+		p.errorAt(p.curToken, "fallthrough statement out of place")
+		return nil
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
-func (p *Parser) parseLetStatement() *ast.LetStatement {
+func (p *Parser) parseLetStatement() ast.Statement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENT) {
+	p.nextToken()
+	if stmt.Name = p.parsePattern(); stmt.Name == nil {
 		return nil
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -192,10 +454,25 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
-	stmt := &ast.ExpressionStatement{Token: p.curToken}
+func (p *Parser) parseWhileStatement() ast.Statement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
 
-	stmt.Expression = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
 
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -204,119 +481,122 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	return stmt
 }
 
-func (p *Parser) parseExpression(precedence int) ast.Expression {
-	prefix := p.prefixParseFns[p.curToken.Type]
-	if prefix == nil {
-		p.noPrefixParseFnError(p.curToken.Type)
+// parseForStatement parses both `for (init; condition; post) { }` and
+// `for (x in iterable) { }`, which starts with an identifier followed by
+// "in" or ",".
+func (p *Parser) parseForStatement() ast.Statement {
+	tok := p.curToken
+
+	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
-	leftExp := prefix()
-
-	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
-		infix := p.infixParseFns[p.peekToken.Type]
-		if infix == nil {
-			return leftExp
-		}
 
-		p.nextToken()
+	p.nextToken()
 
-		leftExp = infix(leftExp)
+	if p.curTokenIs(token.IDENT) && (p.peekTokenIs(token.IN) || p.peekTokenIs(token.COMMA)) {
+		return p.parseForInStatement(tok)
 	}
 
-	return leftExp
-}
+	stmt := &ast.ForStatement{Token: tok}
 
-func (p *Parser) peekPrecedence() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
-		return p
+	if !p.curTokenIs(token.SEMICOLON) {
+		if stmt.Init = p.parseForClause(); stmt.Init == nil {
+			return nil
+		}
+		if !p.curTokenIs(token.SEMICOLON) {
+			p.peekError(token.SEMICOLON)
+			return nil
+		}
 	}
 
-	return LOWEST
-}
+	p.nextToken()
 
-func (p *Parser) curPrecedence() int {
-	if p, ok := precedences[p.curToken.Type]; ok {
-		return p
+	if !p.curTokenIs(token.SEMICOLON) {
+		stmt.Condition = p.parseExpression(LOWEST)
+		if !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
 	}
 
-	return LOWEST
-}
-
-func (p *Parser) parseIdentifier() ast.Expression {
-	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-}
+	p.nextToken()
 
-func (p *Parser) parseIntegerLiteral() ast.Expression {
-	lit := &ast.IntegerLiteral{Token: p.curToken}
+	if !p.curTokenIs(token.RPAREN) {
+		if stmt.Post = p.parseForClause(); stmt.Post == nil {
+			return nil
+		}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
 
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
-	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
-	lit.Value = value
+	stmt.Body = p.parseLoopBody()
 
-	return lit
-}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
 
-func (p *Parser) parseStringLiteral() ast.Expression {
-	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	return stmt
 }
 
-func (p *Parser) parsePrefixExpression() ast.Expression {
-	expression := &ast.PrefixExpression{
-		Token:    p.curToken,
-		Operator: p.curToken.Literal,
+// parseForClause parses the init or post statement of a C-style for loop.
+func (p *Parser) parseForClause() ast.Statement {
+	if p.curTokenIs(token.LET) {
+		return p.parseLetStatement()
 	}
 
-	p.nextToken()
+	return p.parseExpressionStatement()
+}
 
-	expression.Right = p.parseExpression(PREFIX)
+func (p *Parser) parseForInStatement(tok token.Token) ast.Statement {
+	stmt := &ast.ForInStatement{Token: tok}
+	stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
-	return expression
-}
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Key = stmt.Value
+		stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
 
-func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
-	expression := &ast.InfixExpression{
-		Token:    p.curToken,
-		Operator: p.curToken.Literal,
-		Left:     left,
+	if !p.expectPeek(token.IN) {
+		return nil
 	}
 
-	precedence := p.curPrecedence()
 	p.nextToken()
-	expression.Right = p.parseExpression(precedence)
+	stmt.Iterable = p.parseExpression(LOWEST)
 
-	return expression
-}
-
-func (p *Parser) parseBoolean() ast.Expression {
-	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
-}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
 
-func (p *Parser) parseGroupedExpression() ast.Expression {
-	p.nextToken()
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
 
-	exp := p.parseExpression(LOWEST)
+	stmt.Body = p.parseLoopBody()
 
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
 	}
 
-	return exp
+	return stmt
 }
 
-func (p *Parser) parseIfExpression() ast.Expression {
-	expression := &ast.IfExpression{Token: p.curToken}
+func (p *Parser) parseSwitchStatement() ast.Statement {
+	stmt := &ast.SwitchStatement{Token: p.curToken}
 
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
 
 	p.nextToken()
-	expression.Condition = p.parseExpression(LOWEST)
+	stmt.Subject = p.parseExpression(LOWEST)
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
@@ -326,160 +606,1156 @@ func (p *Parser) parseIfExpression() ast.Expression {
 		return nil
 	}
 
-	expression.Consequence = p.parseBlockStatement()
+	blockBraces := p.blockBraces
+	p.blockBraces = p.braces
+	defer func() { p.blockBraces = blockBraces }()
 
-	if p.peekTokenIs(token.ELSE) {
-		p.nextToken()
+	p.nextToken()
 
-		if !p.expectPeek(token.LBRACE) {
+	hasDefault := false
+	for !p.curTokenIs(token.RBRACE) {
+		if !p.atClause() {
+			p.errorAt(p.curToken, "expected case or default, got %s", p.curToken.Type)
 			return nil
 		}
 
-		expression.Alternative = p.parseBlockStatement()
-	}
-
-	return expression
-}
+		if p.curTokenIs(token.DEFAULT) {
+			if hasDefault {
+				p.errorAt(p.curToken, "multiple defaults in switch")
+				return nil
+			}
+			hasDefault = true
+		}
 
-func (p *Parser) parseBlockStatement() *ast.BlockStatement {
-	block := &ast.BlockStatement{Token: p.curToken}
-	block.Statements = []ast.Statement{}
+		c := p.parseSwitchCase()
+		if c == nil {
+			return nil
+		}
+		stmt.Cases = append(stmt.Cases, c)
+	}
 
-	p.nextToken()
+	if n := len(stmt.Cases); n > 0 && stmt.Cases[n-1].Fallthrough {
+		p.errorAt(stmt.Cases[n-1].Token, "cannot fallthrough the last case of a switch")
+	}
 
-	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			block.Statements = append(block.Statements, stmt)
-		}
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
-	return block
+	return stmt
 }
 
-func (p *Parser) parseFunctionLiteral() ast.Expression {
-	lit := &ast.FunctionLiteral{Token: p.curToken}
-
-	if !p.expectPeek(token.LPAREN) {
-		return nil
+// atClause reports whether the current token starts a switch clause:
+// `case`, or `default` followed by a colon. They are only keywords there,
+// so the token is retyped from IDENT to CASE or DEFAULT.
+func (p *Parser) atClause() bool {
+	switch {
+	case p.curTokenIs(token.CASE), p.curTokenIs(token.DEFAULT):
+		return true
+	case !p.curTokenIs(token.IDENT):
+		return false
+	case p.curToken.Literal == "case":
+		p.curToken.Type = token.CASE
+		return true
+	case p.curToken.Literal == "default" && p.peekTokenIs(token.COLON):
+		p.curToken.Type = token.DEFAULT
+		return true
+	default:
+		return false
 	}
+}
 
-	lit.Parameters = p.parseFunctionParameters()
-
-	if !p.expectPeek(token.LBRACE) {
-		return nil
+// peekStartsClause reports whether, inside a switch, the next token may
+// start a clause, which makes it a place to resume after an error.
+func (p *Parser) peekStartsClause() bool {
+	if !p.peekTokenIs(token.IDENT) || (p.peekToken.Literal != "case" && p.peekToken.Literal != "default") {
+		return false
 	}
-
-	lit.Body = p.parseBlockStatement()
-
-	return lit
+	for _, kind := range p.blocks {
+		if kind == switchBlock {
+			return true
+		}
+	}
+	return false
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+// parseSwitchCase parses a case clause, leaving the parser on the token
+// after its body: the next case, default, or the closing '}'.
+func (p *Parser) parseSwitchCase() *ast.SwitchCase {
+	c := &ast.SwitchCase{Token: p.curToken}
 
-	if p.peekTokenIs(token.RPAREN) {
+	if p.curTokenIs(token.CASE) {
 		p.nextToken()
-		return identifiers
+		c.Values = append(c.Values, p.parseExpression(LOWEST))
+
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			p.nextToken()
+			c.Values = append(c.Values, p.parseExpression(LOWEST))
+		}
+	}
+
+	if !p.expectPeek(token.COLON) {
+		return nil
 	}
 
+	p.blocks = append(p.blocks, switchBlock)
+	defer func() { p.blocks = p.blocks[:len(p.blocks)-1] }()
+
+	c.Body = &ast.BlockStatement{Token: p.curToken, Statements: []ast.Statement{}}
 	p.nextToken()
 
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+	for !p.atClause() && !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+
+		if p.curTokenIs(token.FALLTHROUGH) {
+			c.Fallthrough = true
+			if p.peekTokenIs(token.SEMICOLON) {
+				p.nextToken()
+			}
+			p.nextToken()
+			if !p.atClause() && !p.curTokenIs(token.RBRACE) {
+				p.errorAt(p.curToken, "fallthrough must be the last statement of a case")
+				return nil
+			}
+			break
+		}
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
+		brackets := p.brackets
+		stmt := p.parseStatement()
+		if p.panicking {
+			stmt = partialStatement(stmt)
+			p.synchronize(brackets)
+			if p.braces < p.blockBraces {
+				// the error was on the '}' closing the block
+				break
+			}
+		}
+		if stmt != nil {
+			c.Body.Statements = append(c.Body.Statements, stmt)
+		}
 		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if p.curTokenIs(token.EOF) {
+		p.errorAt(p.curToken, "expected }, got EOF")
 		return nil
 	}
 
-	return identifiers
+	return c
 }
 
-func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
-	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	// exp.Arguments = p.parseCallArguments()
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
-	return exp
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.blocks = append(p.blocks, loopBlock)
+	defer func() { p.blocks = p.blocks[:len(p.blocks)-1] }()
+
+	return p.parseBlockStatement()
 }
 
-// func (p *Parser) parseCallArguments() []ast.Expression {
-// 	args := []ast.Expression{}
+func (p *Parser) parseBreakStatement() ast.Statement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
 
-// 	if p.peekTokenIs(token.RPAREN) {
-// 		p.nextToken()
-// 		return args
-// 	}
+	p.checkJump(false)
 
-// 	p.nextToken()
-// 	args = append(args, p.parseExpression(LOWEST))
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
 
-// 	for p.peekTokenIs(token.COMMA) {
-// 		p.nextToken()
-// 		p.nextToken()
-// 		args = append(args, p.parseExpression(LOWEST))
-// 	}
+	return stmt
+}
 
-// 	if !p.expectPeek(token.RPAREN) {
-// 		return nil
-// 	}
+// checkJump reports a break, or a continue, that has no loop or switch
+// to leave, or that would leave the body of an if expression whose value
+// is used, which would make the break or continue that value instead.
+func (p *Parser) checkJump(continuing bool) {
+	inValue := false
+	for i := len(p.blocks) - 1; i >= 0; i-- {
+		switch p.blocks[i] {
+		case valueBlock:
+			inValue = true
+			continue
+		case switchBlock:
+			if continuing {
+				continue
+			}
+		}
 
-// 	return args
-// }
+		if inValue {
+			p.errorAt(p.curToken, "%s used as a value", p.curToken.Literal)
+		}
+		return
+	}
 
-func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
-	p.prefixParseFns[tokenType] = fn
+	if continuing {
+		p.errorAt(p.curToken, "continue outside loop")
+	} else {
+		p.errorAt(p.curToken, "break outside loop or switch")
+	}
 }
 
-func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
-	p.infixParseFns[tokenType] = fn
-}
+func (p *Parser) parseContinueStatement() ast.Statement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
 
+	p.checkJump(true)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+
+	p.ifStatement = p.curTokenIs(token.IF)
+	stmt.Expression = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseExpression(precedence int) ast.Expression {
+	prefix := p.prefixParseFns[p.curToken.Type]
+	if prefix == nil {
+		p.noPrefixParseFnError(p.curToken.Type)
+		return nil
+	}
+	leftExp := prefix()
+
+	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+		infix := p.infixParseFns[p.peekToken.Type]
+		if infix == nil {
+			return leftExp
+		}
+
+		p.nextToken()
+
+		leftExp = infix(leftExp)
+	}
+
+	return leftExp
+}
+
+func (p *Parser) peekPrecedence() int {
+	if p, ok := precedences[p.peekToken.Type]; ok {
+		return p
+	}
+
+	return LOWEST
+}
+
+func (p *Parser) curPrecedence() int {
+	if p, ok := precedences[p.curToken.Type]; ok {
+		return p
+	}
+
+	return LOWEST
+}
+
+func (p *Parser) parseIdentifier() ast.Expression {
+	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parseIntegerLiteral() ast.Expression {
+	lit := &ast.IntegerLiteral{Token: p.curToken}
+
+	base, digits, err := splitIntegerLiteral(p.curToken.Literal)
+	if err != nil {
+		p.errorAt(p.curToken, "invalid integer literal %q: %s", p.curToken.Literal, err)
+		return nil
+	}
+
+	value, err := strconv.ParseInt(digits, base, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if bigValue, ok := new(big.Int).SetString(digits, base); ok {
+			return &ast.BigIntegerLiteral{Token: p.curToken, Value: bigValue}
+		}
+	}
+	if err != nil {
+		p.errorAt(p.curToken, "could not parse %q as integer", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+// splitIntegerLiteral validates an integer literal such as 1_000, 0xFF,
+// 0o755 or 0b1010 and returns its base and its digits without the prefix
+// and separators. Literals without a prefix are always decimal.
+func splitIntegerLiteral(literal string) (int, string, error) {
+	base, name, body := 10, "decimal", literal
+
+	if len(literal) >= 2 && literal[0] == '0' {
+		switch literal[1] {
+		case 'x', 'X':
+			base, name, body = 16, "hexadecimal", literal[2:]
+		case 'o', 'O':
+			base, name, body = 8, "octal", literal[2:]
+		case 'b', 'B':
+			base, name, body = 2, "binary", literal[2:]
+		}
+	}
+
+	if strings.Trim(body, "_") == "" {
+		return 0, "", fmt.Errorf("%s literal has no digits", name)
+	}
+
+	for i, ch := range body {
+		if ch == '_' {
+			// '_' may follow a prefix, otherwise it goes between digits
+			if i == len(body)-1 || body[i+1] == '_' || i == 0 && base == 10 {
+				return 0, "", fmt.Errorf("'_' must separate successive digits")
+			}
+			continue
+		}
+
+		if digitValue(ch) >= base {
+			return 0, "", fmt.Errorf("invalid digit %q in %s literal", ch, name)
+		}
+	}
+
+	return base, strings.ReplaceAll(body, "_", ""), nil
+}
+
+func digitValue(ch rune) int {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'z':
+		return int(ch - 'a' + 10)
+	case 'A' <= ch && ch <= 'Z':
+		return int(ch - 'A' + 10)
+	default:
+		return 36
+	}
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		p.errorAt(p.curToken, "float literal %q is out of range", p.curToken.Literal)
+		return nil
+	}
+	if err != nil {
+		p.errorAt(p.curToken, "invalid float literal %q", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+
+	for {
+		if p.curToken.Literal != "" {
+			str.Parts = append(str.Parts, p.parseStringLiteral())
+		}
+
+		if p.curTokenIs(token.STRING) {
+			break
+		}
+
+		// the token ends just past the "${"
+		open := p.curToken.End
+		open.Offset -= 2
+		open.Column -= 2
+
+		p.interpolations = append(p.interpolations, open)
+		p.nextToken()
+		part := p.parseExpression(LOWEST)
+		p.interpolations = p.interpolations[:len(p.interpolations)-1]
+
+		if !p.peekTokenIs(token.INTERPOLATION_END) {
+			p.incompleteInterpolationError(open)
+			return nil
+		}
+		str.Parts = append(str.Parts, part)
+		p.nextToken()
+		p.nextToken()
+	}
+
+	return str
+}
+
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+	}
+
+	p.nextToken()
+
+	expression.Right = p.parseExpression(PREFIX)
+
+	return expression
+}
+
+func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
+	expression := &ast.InfixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+
+	precedence := p.curPrecedence()
+	if p.curTokenIs(token.POWER) {
+		// right-associative: 2 ** 3 ** 2 is 2 ** 9
+		precedence--
+	}
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+
+	return expression
+}
+
+// parseAssignExpression parses an assignment, which is right-associative:
+// a = b = c assigns c to b and then to a.
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Target:   target,
+	}
+
+	switch target.(type) {
+	case *ast.Identifier:
+	case *ast.IndexExpression:
+		if target.(*ast.IndexExpression).Optional {
+			p.errorAt(p.curToken, "cannot assign to %s", target)
+			return nil
+		}
+	case *ast.MemberExpression:
+		if target.(*ast.MemberExpression).Optional {
+			p.errorAt(p.curToken, "cannot assign to %s", target)
+			return nil
+		}
+	case nil:
+		// the target is broken, and already reported
+		return nil
+	default:
+		p.errorAt(p.curToken, "cannot assign to %s", target)
+		return nil
+	}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
+func (p *Parser) parseBoolean() ast.Expression {
+	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
+}
+
+func (p *Parser) parseGroupedExpression() ast.Expression {
+	p.nextToken()
+
+	exp := p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return exp
+}
+
+func (p *Parser) parseIfExpression() ast.Expression {
+	expression := &ast.IfExpression{Token: p.curToken}
+
+	statement := p.ifStatement
+	p.ifStatement = false
+	if !statement {
+		p.blocks = append(p.blocks, valueBlock)
+		defer func() { p.blocks = p.blocks[:len(p.blocks)-1] }()
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Consequence = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.ELSE) {
+		p.nextToken()
+
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+
+			p.ifStatement = statement
+			elseIf, ok := p.parseIfExpression().(*ast.IfExpression)
+			if !ok {
+				return nil
+			}
+			expression.ElseIf = elseIf
+
+			return expression
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Alternative = p.parseBlockStatement()
+	}
+
+	return expression
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expression.Arms = append(expression.Arms, arm)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return expression
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Token: p.curToken}
+
+	if arm.Pattern = p.parsePattern(); arm.Pattern == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	p.nextToken()
+	arm.Body = p.parseExpression(LOWEST)
+
+	return arm
+}
+
+// parsePattern parses the pattern starting at the current token.
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		if p.curToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curToken}
+		}
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE:
+		return p.parseLiteralPattern()
+	case token.MINUS:
+		if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.FLOAT) {
+			break
+		}
+		return p.parseLiteralPattern()
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseMapPattern()
+	}
+
+	p.errorAt(p.curToken, "expected a pattern, got %s", p.curToken.Type)
+	return nil
+}
+
+func (p *Parser) parseLiteralPattern() ast.Pattern {
+	pattern := &ast.LiteralPattern{Token: p.curToken}
+
+	if pattern.Value = p.prefixParseFns[p.curToken.Type](); pattern.Value == nil {
+		return nil
+	}
+
+	return pattern
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		if n := len(pattern.Elements); n > 0 {
+			if _, ok := pattern.Elements[n-1].(*ast.RestPattern); ok {
+				p.errorAt(p.peekToken, "rest pattern must be the last element")
+				return nil
+			}
+		}
+
+		p.nextToken()
+
+		var element ast.Pattern
+		if p.curTokenIs(token.ELLIPSIS) {
+			element = p.parseRestPattern()
+		} else {
+			element = p.parsePattern()
+		}
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+
+	return pattern
+}
+
+func (p *Parser) parseRestPattern() *ast.RestPattern {
+	pattern := &ast.RestPattern{Token: p.curToken}
+
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		pattern.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	return pattern
+}
+
+// parseMapPattern parses `{"key": pattern, name}`, where a lone name is
+// short for `"name": name`.
+func (p *Parser) parseMapPattern() ast.Pattern {
+	pattern := &ast.MapPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		var key ast.Expression
+		var value ast.Pattern
+
+		switch p.curToken.Type {
+		case token.IDENT:
+			ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			key = &ast.StringLiteral{Token: p.curToken, Value: ident.Value}
+			value = ident
+		case token.STRING, token.INT, token.TRUE, token.FALSE:
+			key = p.prefixParseFns[p.curToken.Type]()
+
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+
+			p.nextToken()
+			if value = p.parsePattern(); value == nil {
+				return nil
+			}
+		default:
+			p.errorAt(p.curToken, "expected a map key, got %s", p.curToken.Type)
+			return nil
+		}
+
+		pattern.Keys = append(pattern.Keys, key)
+		pattern.Values = append(pattern.Values, value)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+
+	return pattern
+}
+
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: p.curToken}
+	block.Statements = []ast.Statement{}
+
+	blockBraces := p.blockBraces
+	p.blockBraces = p.braces
+	defer func() { p.blockBraces = blockBraces }()
+
+	p.nextToken()
+
+	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		brackets := p.brackets
+		stmt := p.parseStatement()
+		if p.panicking {
+			stmt = partialStatement(stmt)
+			p.synchronize(brackets)
+			if p.braces < p.blockBraces {
+				// the error was on the '}' closing the block
+				break
+			}
+		}
+		if stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
+		p.nextToken()
+	}
+
+	return block
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	lit := &ast.FunctionLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	lit.Parameters = p.parseFunctionParameters()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	lit.Body = p.parseFunctionBody()
+
+	return lit
+}
+
+// parseFunctionBody parses the body of a function or macro, where break
+// and continue can't reach the loops around the literal.
+func (p *Parser) parseFunctionBody() *ast.BlockStatement {
+	blocks := p.blocks
+	p.blocks = nil
+	defer func() { p.blocks = blocks }()
+
+	return p.parseBlockStatement()
+}
+
+func (p *Parser) parseFunctionParameters() []ast.Pattern {
+	params := []ast.Pattern{}
+	names := map[string]bool{}
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return params
+	}
+
+	for {
+		p.nextToken()
+
+		param := p.parseParameter()
+		if param == nil {
+			return nil
+		}
+		params = append(params, param)
+
+		for _, name := range patternNames(param) {
+			if names[name.Value] {
+				p.errorAt(name.Token, "duplicate parameter %s", name.Value)
+				return nil
+			}
+			names[name.Value] = true
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		if _, ok := param.(*ast.RestPattern); ok {
+			p.errorAt(p.peekToken, "rest parameter must be the last parameter")
+			return nil
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return params
+}
+
+// parseParameter parses a pattern with an optional default value,
+// `y = 10`, or a rest parameter, `...others`.
+func (p *Parser) parseParameter() ast.Pattern {
+	if p.curTokenIs(token.ELLIPSIS) {
+		return p.parseRestPattern()
+	}
+
+	param := p.parsePattern()
+	if param == nil || !p.peekTokenIs(token.ASSIGN) {
+		return param
+	}
+
+	p.nextToken()
+	dp := &ast.DefaultPattern{Token: p.curToken, Pattern: param}
+
+	p.nextToken()
+	if dp.Default = p.parseExpression(LOWEST); dp.Default == nil {
+		return nil
+	}
+
+	return dp
+}
+
+// patternNames returns the identifiers that pattern binds, in order.
+func patternNames(pattern ast.Pattern) []*ast.Identifier {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		return []*ast.Identifier{pattern}
+	case *ast.DefaultPattern:
+		return patternNames(pattern.Pattern)
+	case *ast.RestPattern:
+		if pattern.Name != nil {
+			return []*ast.Identifier{pattern.Name}
+		}
+	case *ast.ArrayPattern:
+		names := []*ast.Identifier{}
+		for _, element := range pattern.Elements {
+			names = append(names, patternNames(element)...)
+		}
+		return names
+	case *ast.MapPattern:
+		names := []*ast.Identifier{}
+		for _, value := range pattern.Values {
+			names = append(names, patternNames(value)...)
+		}
+		return names
+	}
+	return nil
+}
+
+// parseMacroParameters parses the parameters of a macro, which are bound
+// to quoted arguments and so are plain identifiers.
+func (p *Parser) parseMacroParameters() []*ast.Identifier {
+	identifiers := []*ast.Identifier{}
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return identifiers
+	}
+
+	for {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		identifiers = append(identifiers, ident)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return identifiers
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+	exp.Arguments = p.parseCallArguments()
+	return exp
+}
+
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	exp := &ast.PipeExpression{Token: p.curToken, Left: left}
+
+	precedence := p.curPrecedence()
+	p.nextToken()
+	exp.Right = p.parseExpression(precedence)
+
+	return exp
+}
+
+// parseCallArguments parses positional arguments followed by keyword
+// arguments, `f(1, y: 2)`.
+func (p *Parser) parseCallArguments() []ast.Expression {
+	args := []ast.Expression{}
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return args
+	}
+
+	keywords := map[string]bool{}
+	for {
+		p.nextToken()
+
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+			arg := p.parseKeywordArgument()
+			if arg == nil {
+				return nil
+			}
+			if keywords[arg.Name.Value] {
+				p.errorAt(arg.Token, "duplicate keyword argument %s", arg.Name)
+				return nil
+			}
+			keywords[arg.Name.Value] = true
+			args = append(args, arg)
+		} else {
+			if len(keywords) > 0 {
+				p.errorAt(p.curToken, "positional argument follows keyword argument")
+				return nil
+			}
+			args = append(args, p.parseElement())
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return args
+}
+
+func (p *Parser) parseKeywordArgument() *ast.KeywordArgument {
+	arg := &ast.KeywordArgument{Token: p.curToken}
+	arg.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	p.nextToken()
+	p.nextToken()
+
+	if arg.Value = p.parseExpression(LOWEST); arg.Value == nil {
+		return nil
+	}
+
+	return arg
+}
+
+func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
+	p.prefixParseFns[tokenType] = fn
+}
+
+func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
+	p.infixParseFns[tokenType] = fn
+}
+
+// parseArrayLiteral parses an array literal, or a list comprehension
+// `[element for x in xs if condition]`.
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = []ast.Expression{}
+
+	if p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		return array
+	}
+
+	p.nextToken()
+	first := p.parseElement()
+
+	if spread, ok := first.(*ast.SpreadElement); ok && p.peekTokenIs(token.FOR) {
+		p.errorAt(spread.Token, "cannot spread the element of a comprehension")
+		return nil
+	}
+
+	if p.peekTokenIs(token.FOR) {
+		lc := &ast.ListComprehension{Token: array.Token, Element: first}
+		if lc.Clause = p.parseComprehensionClause(); lc.Clause == nil {
+			return nil
+		}
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		return lc
+	}
+
+	array.Elements = append(array.Elements, first)
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		array.Elements = append(array.Elements, p.parseElement())
+	}
 
-	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if !p.expectPeek(token.RBRACKET) {
+		array.Elements = nil
+	}
 
 	return array
 }
 
-func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
-	list := []ast.Expression{}
+// parseComprehensionClause parses `for x in xs if condition` after the
+// element of a comprehension. The condition is optional.
+func (p *Parser) parseComprehensionClause() *ast.ComprehensionClause {
+	p.nextToken()
+	clause := &ast.ComprehensionClause{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	clause.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
-	if p.peekTokenIs(end) {
+	if p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		return list
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		clause.Key = clause.Value
+		clause.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	clause.Iterable = p.parseExpression(LOWEST)
 
-	for p.peekTokenIs(token.COMMA) {
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		clause.Condition = p.parseExpression(LOWEST)
+	}
+
+	return clause
+}
+
+func (p *Parser) parseRangeExpression(left ast.Expression) ast.Expression {
+	exp := &ast.RangeExpression{
+		Token:     p.curToken,
+		Start:     left,
+		Inclusive: p.curTokenIs(token.RANGE_INCLUSIVE),
+	}
+
+	precedence := p.curPrecedence()
+	p.nextToken()
+	exp.End = p.parseExpression(precedence)
+
+	return exp
+}
+
+// parseOptionalChain parses `left?.[index]`, `left?.(arguments)` and
+// `left?.property`.
+func (p *Parser) parseOptionalChain(left ast.Expression) ast.Expression {
+	switch {
+	case p.peekTokenIs(token.IDENT):
+		exp := p.parseMemberExpression(left)
+		exp.(*ast.MemberExpression).Optional = true
+		return exp
+	case p.peekTokenIs(token.LBRACKET):
 		p.nextToken()
+		exp := p.parseIndexExpression(left)
+		switch exp := exp.(type) {
+		case *ast.IndexExpression:
+			exp.Optional = true
+		case *ast.SliceExpression:
+			exp.Optional = true
+		}
+		return exp
+	case p.peekTokenIs(token.LPAREN):
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		exp := p.parseCallExpression(left)
+		exp.(*ast.CallExpression).Optional = true
+		return exp
+	default:
+		p.errorAt(p.peekToken, "expected a name, [ or ( after ?., got %s", p.peekToken.Type)
+		return nil
 	}
+}
 
-	if !p.expectPeek(end) {
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: object}
+
+	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
+// parseElement parses an element of an array literal or argument list,
+// which may be spread.
+func (p *Parser) parseElement() ast.Expression {
+	if p.curTokenIs(token.ELLIPSIS) {
+		return p.parseSpreadElement()
+	}
+	return p.parseExpression(LOWEST)
+}
 
-	return list
+func (p *Parser) parseSpreadElement() ast.Expression {
+	spread := &ast.SpreadElement{Token: p.curToken}
+
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+
+	return spread
 }
 
+// parseIndexExpression parses `left[index]`, or a slice `left[start:end]`
+// where either bound may be left out.
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	tok := p.curToken
+
+	var index ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		index = p.parseExpression(LOWEST)
+	}
+
+	if !p.peekTokenIs(token.COLON) {
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		return &ast.IndexExpression{Token: tok, Left: left, Index: index}
+	}
 
+	exp := &ast.SliceExpression{Token: tok, Left: left, Start: index}
 	p.nextToken()
 
-	exp.Index = p.parseExpression(LOWEST)
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		exp.End = p.parseExpression(LOWEST)
+	}
 
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
@@ -488,22 +1764,43 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseMapLiteral parses a map literal, or a map comprehension
+// `{key: value for k, v in m if condition}`.
 func (p *Parser) parseMapLiteral() ast.Expression {
 	hash := &ast.MapLiteral{Token: p.curToken}
 	hash.Pairs = make(map[ast.Expression]ast.Expression)
+	hash.Keys = []ast.Expression{}
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
-		key := p.parseExpression(LOWEST)
 
-		if !p.expectPeek(token.COLON) {
-			return nil
+		if p.curTokenIs(token.ELLIPSIS) {
+			hash.Keys = append(hash.Keys, p.parseSpreadElement())
+		} else {
+			key := p.parseExpression(LOWEST)
+
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+
+			p.nextToken()
+			value := p.parseExpression(LOWEST)
+
+			if len(hash.Keys) == 0 && p.peekTokenIs(token.FOR) {
+				mc := &ast.MapComprehension{Token: hash.Token, Key: key, Value: value}
+				if mc.Clause = p.parseComprehensionClause(); mc.Clause == nil {
+					return nil
+				}
+				if !p.expectPeek(token.RBRACE) {
+					return nil
+				}
+				return mc
+			}
+
+			hash.Pairs[key] = value
+			hash.Keys = append(hash.Keys, key)
 		}
 
-		p.nextToken()
-		value := p.parseExpression(LOWEST)
-		hash.Pairs[key] = value
-
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
 		}
@@ -523,13 +1820,13 @@ func (p *Parser) parseMacroLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	lit.Parameters = p.parseMacroParameters()
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
-	lit.Body = p.parseBlockStatement()
+	lit.Body = p.parseFunctionBody()
 
 	return lit
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"lemon/ast"
 	"lemon/lexer"
+	"lemon/token"
 	"testing"
 )
 
@@ -41,6 +42,35 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuringPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = xs;", "let [a, b] = xs;"},
+		{"let [a, ...rest] = xs;", "let [a, ...rest] = xs;"},
+		{`let {"name": n, age} = person;`, `let {"name": n, "age": age} = person;`},
+		{"let [[a, b], _] = xs;", "let [[a, b], _] = xs;"},
+		{"fn([a, b], {x}) { a }", `fn([a, b], {"x": x}) a`},
+		{"fn(x, y = 10) { x }", "fn(x, y = 10) x"},
+		{"fn(x, [a, b] = [1, 2], ...rest) { x }", "fn(x, [a, b] = [1, 2], ...rest) x"},
+		{"f(1, y: 2, z: x + 1)", "f(1, y: 2, z: (x + 1))"},
+		{"f(y: g(a: 1))", "f(y: g(a: 1))"},
+		{"f({a: 1})", "f({a: 1})"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -139,6 +169,64 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14;", 3.14},
+		{"1e3;", 1000},
+		{"2.5e-1;", 0.25},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestIntegerLiteralBases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xFF;", 255},
+		{"0XfF;", 255},
+		{"0o755;", 493},
+		{"0b1010;", 10},
+		{"0x_FF;", 255},
+		{"1_000_000;", 1000000},
+		{"0755;", 755},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %d. got=%d", tt.expected, literal.Value)
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -249,6 +337,106 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"!-a",
 			"(!(-a))",
 		},
+		{
+			`a?.["k"]?.[0]`,
+			`((a?.["k"])?.[0])`,
+		},
+		{
+			"f?.(x)?.[1:]",
+			"(f?.(x)?.[1:])",
+		},
+		{
+			"a ?? b || c",
+			"(a ?? (b || c))",
+		},
+		{
+			"a ?? b ?? c |> f",
+			"(((a ?? b) ?? c) |> f)",
+		},
+		{
+			"x = a?.[0] ?? 1",
+			"(x = ((a?.[0]) ?? 1))",
+		},
+		{
+			"[...a, b, ...c + d]",
+			"[...a, b, ...(c + d)]",
+		},
+		{
+			`{...defaults, "k": v, ...more}`,
+			`{...defaults, "k": v, ...more}`,
+		},
+		{
+			"f(...args, x)",
+			"f(...args, x)",
+		},
+		{
+			"[x * 2 for x in xs if x > 0 && x < 9]",
+			"[(x * 2) for x in xs if ((x > 0) && (x < 9))]",
+		},
+		{
+			"[[i, c] for i, c in 0..n]",
+			"[[i, c] for i, c in (0..n)]",
+		},
+		{
+			"{k: v + 1 for k, v in m}",
+			"{k: (v + 1) for k, v in m}",
+		},
+		{
+			"a.b.c",
+			"((a.b).c)",
+		},
+		{
+			"a.b(c).d[0]",
+			"(((a.b)(c).d)[0])",
+		},
+		{
+			"-a.b * c",
+			"((-(a.b)) * c)",
+		},
+		{
+			"a?.b.c?.(1)",
+			"((a?.b).c)?.(1)",
+		},
+		{
+			"m.x = 1",
+			"((m.x) = 1)",
+		},
+		{
+			"0..n - 1",
+			"(0..(n - 1))",
+		},
+		{
+			"i < 1..=n == x",
+			"((i < (1..=n)) == x)",
+		},
+		{
+			"a[1:2]",
+			"(a[1:2])",
+		},
+		{
+			"a[:-1] + s[i + 1:]",
+			"((a[:(-1)]) + (s[(i + 1):]))",
+		},
+		{
+			"a[:]",
+			"(a[:])",
+		},
+		{
+			"a[1..3]",
+			"(a[(1..3)])",
+		},
+		{
+			"a |> f(b) |> g",
+			"((a |> f(b)) |> g)",
+		},
+		{
+			"x = a + 1 |> f(b * 2)",
+			"(x = ((a + 1) |> f((b * 2))))",
+		},
+		{
+			"a || b |> f()",
+			"((a || b) |> f())",
+		},
 		{
 			"a + b + c",
 			"((a + b) + c)",
@@ -329,6 +517,50 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"!(true == true)",
 			"(!(true == true))",
 		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a + b % c ** d ** e",
+			"(a + (b % (c ** (d ** e))))",
+		},
+		{
+			"-a ** b",
+			"(-(a ** b))",
+		},
+		{
+			"a ** -b",
+			"(a ** (-b))",
+		},
+		{
+			"a | b ^ c & d == e",
+			"(a | (b ^ (c & (d == e))))",
+		},
+		{
+			"a << b + c < d >> e",
+			"((a << (b + c)) < (d >> e))",
+		},
+		{
+			"a || b | c",
+			"(a || (b | c))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a == b && c < d || !e",
+			"(((a == b) && (c < d)) || (!e))",
+		},
+		{
+			"x = a || b",
+			"(x = (a || b))",
+		},
 		{
 			"a + add(b * c) + d",
 			"((a + add((b * c))) + d)",
@@ -509,6 +741,303 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestIfElseIfExpression(t *testing.T) {
+	input := `if (x < y) { x } else if (x > y) { y } else if (z) { z } else { 0 }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	if exp.Alternative != nil {
+		t.Errorf("exp.Alternative is not nil. got=%+v", exp.Alternative)
+	}
+
+	second := exp.ElseIf
+	if second == nil {
+		t.Fatalf("exp.ElseIf is nil")
+	}
+
+	if !testInfixExpression(t, second.Condition, "x", ">", "y") {
+		return
+	}
+
+	third := second.ElseIf
+	if third == nil {
+		t.Fatalf("second.ElseIf is nil")
+	}
+
+	if !testIdentifier(t, third.Condition, "z") {
+		return
+	}
+
+	if third.ElseIf != nil || third.Alternative == nil {
+		t.Fatalf("third branch should end with an else block. got=%s", third)
+	}
+
+	expected := "if ((x < y)) { x } else if ((x > y)) { y } else if (z) { z } else { 0 }"
+	if exp.String() != expected {
+		t.Errorf("exp.String() wrong. expected=%q, got=%q", expected, exp.String())
+	}
+}
+
+func TestAssignExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5;", "(x = 5)"},
+		{"x += y * 2;", "(x += (y * 2))"},
+		{"x -= 1", "(x -= 1)"},
+		{"x *= 2", "(x *= 2)"},
+		{"x /= 2", "(x /= 2)"},
+		{"a = b = c", "(a = (b = c))"},
+		{"arr[0] = 1 + 2", "((arr[0]) = (1 + 2))"},
+		{`m["k"] += 1`, `((m["k"]) += 1)`},
+		{"x = y == z", "(x = (y == z))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.AssignExpression); !ok {
+			t.Fatalf("stmt.Expression is not ast.AssignExpression. got=%T", stmt.Expression)
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedArms int
+		expected     string
+	}{
+		{
+			`match (x) { 0 => "zero", -1 => "minus one", n if n > 0 => "positive", _ => "other" }`,
+			4,
+			`match (x) { 0 => "zero", (-1) => "minus one", n if (n > 0) => "positive", _ => "other" }`,
+		},
+		{
+			"match (xs) { [] => 0, [x] => x, [first, ...rest] => first, [...] => 1, }",
+			4,
+			"match (xs) { [] => 0, [x] => x, [first, ...rest] => first, [...] => 1 }",
+		},
+		{
+			`match (user) { {"name": "root"} => true, {name, "age": [a, _]} => name, {} => false }`,
+			3,
+			`match (user) { {"name": "root"} => true, {"name": name, "age": [a, _]} => name, {} => false }`,
+		},
+		{
+			`match (v) { true => 1, 2.5 => {"k": 1}, "s" => [1] }`,
+			3,
+			`match (v) { true => 1, 2.5 => {"k": 1}, "s" => [1] }`,
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.MatchExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+		}
+
+		if len(exp.Arms) != tt.expectedArms {
+			t.Errorf("wrong number of arms. expected=%d, got=%d", tt.expectedArms, len(exp.Arms))
+		}
+
+		if exp.String() != tt.expected {
+			t.Errorf("exp.String() wrong. expected=%q, got=%q", tt.expected, exp.String())
+		}
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { x; break; continue }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.WhileStatement. got=%T",
+			program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 3 {
+		t.Fatalf("body does not contain 3 statements. got=%d", len(stmt.Body.Statements))
+	}
+
+	if _, ok := stmt.Body.Statements[1].(*ast.BreakStatement); !ok {
+		t.Errorf("body.Statements[1] is not *ast.BreakStatement. got=%T",
+			stmt.Body.Statements[1])
+	}
+
+	if _, ok := stmt.Body.Statements[2].(*ast.ContinueStatement); !ok {
+		t.Errorf("body.Statements[2] is not *ast.ContinueStatement. got=%T",
+			stmt.Body.Statements[2])
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (let i = 0; i < 10; let i = i + 1) { i }", "for (let i = 0; (i < 10); let i = (i + 1)) { i }"},
+		{"for (i; i; i) { }", "for (i; i; i) {  }"},
+		{"for (;;) { break; }", "for (; ; ) { break; }"},
+		{"for (; x;) { x }", "for (; x; ) { x }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.ForStatement. got=%T",
+				program.Statements[0])
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestForInStatement(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedKey   string
+		expectedValue string
+	}{
+		{"for (x in [1, 2]) { x }", "", "x"},
+		{"for (i, x in xs) { x }", "i", "x"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ForInStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.ForInStatement. got=%T",
+				program.Statements[0])
+		}
+
+		if tt.expectedKey == "" {
+			if stmt.Key != nil {
+				t.Errorf("stmt.Key is not nil. got=%s", stmt.Key)
+			}
+		} else if !testIdentifier(t, stmt.Key, tt.expectedKey) {
+			return
+		}
+
+		if !testIdentifier(t, stmt.Value, tt.expectedValue) {
+			return
+		}
+
+		if len(stmt.Body.Statements) != 1 {
+			t.Errorf("body does not contain 1 statement. got=%d", len(stmt.Body.Statements))
+		}
+	}
+}
+
+func TestTrailingSemicolons(t *testing.T) {
+	inputs := []string{
+		"while (c) { x }; y",
+		"for (x in xs) { }; y",
+		"for (;;) { break; }; y",
+		"switch (1) { case 1: println(1) }; y",
+	}
+
+	for _, input := range inputs {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 2 {
+			t.Errorf("program.Statements does not contain 2 statements for %q. got=%d",
+				input, len(program.Statements))
+		}
+	}
+}
+
+func TestSwitchStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"switch (x) { case 1: a; b; case 2, 3: c default: d }", "switch (x) { case 1: ab case 2, 3: c default: d }"},
+		{"switch (x) { case 1: fallthrough; case 2: break; }", "switch (x) { case 1: fallthrough; case 2: break; }"},
+		{"switch (x) { default: }", "switch (x) { default: }"},
+		{"switch (x + 1) { }", "switch ((x + 1)) { }"},
+		{"switch (x) { case default: default }", "switch (x) { case default: default }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.SwitchStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.SwitchStatement. got=%T",
+				program.Statements[0])
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -539,8 +1068,8 @@ func TestFunctionLiteralParsing(t *testing.T) {
 			len(function.Parameters))
 	}
 
-	testLiteralExpression(t, function.Parameters[0], "x")
-	testLiteralExpression(t, function.Parameters[1], "y")
+	testLiteralExpression(t, function.Parameters[0].(*ast.Identifier), "x")
+	testLiteralExpression(t, function.Parameters[1].(*ast.Identifier), "y")
 
 	if len(function.Body.Statements) != 1 {
 		t.Fatalf("function.Body.Statements has not 1 statements. got=%d\n",
@@ -581,7 +1110,7 @@ func TestFunctionParameterParsing(t *testing.T) {
 		}
 
 		for i, ident := range tt.expectedParams {
-			testLiteralExpression(t, function.Parameters[i], ident)
+			testLiteralExpression(t, function.Parameters[i].(*ast.Identifier), ident)
 		}
 	}
 }
@@ -690,8 +1219,14 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 		return false
 	}
 
-	if letStmt.Name.Value != name {
-		t.Errorf("letStmt.Name.Value not '%s'. got=%s", name, letStmt.Name.Value)
+	ident, ok := letStmt.Name.(*ast.Identifier)
+	if !ok {
+		t.Errorf("letStmt.Name not *ast.Identifier. got=%T", letStmt.Name)
+		return false
+	}
+
+	if ident.Value != name {
+		t.Errorf("letStmt.Name.Value not '%s'. got=%s", name, ident.Value)
 		return false
 	}
 
@@ -917,7 +1452,7 @@ func TestParsingMapLiteralsStringKeys(t *testing.T) {
 		if !ok {
 			t.Errorf("key is not asts.StringLiteral. got=%T", key)
 		}
-		expectedValue := expected[literal.String()]
+		expectedValue := expected[literal.Value]
 		testIntegerLiteral(t, value, expectedValue)
 	}
 }
@@ -976,9 +1511,9 @@ func TestParsingMapLiteralsWithExpressions(t *testing.T) {
 		if !ok {
 			t.Errorf("key is not asts.StringLiteral. got=%T", key)
 		}
-		testFunc, ok := tests[literal.String()]
+		testFunc, ok := tests[literal.Value]
 		if !ok {
-			t.Errorf("No test function for key %q found", literal.String())
+			t.Errorf("No test function for key %q found", literal.Value)
 		}
 
 		testFunc(value)
@@ -1025,3 +1560,288 @@ func TestMacroLiteralParsing(t *testing.T) {
 
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
+
+func TestParserErrorPositions(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let = 5;", "1:5: expected a pattern, got ="},
+		{"fn(...a, b) { }", "1:8: rest parameter must be the last parameter"},
+		{"fn(x, x) { }", "1:7: duplicate parameter x"},
+		{"fn(a, [b, ...a]) { }", "1:14: duplicate parameter a"},
+		{"fn({\"k\": v}, v = 1) { }", "1:14: duplicate parameter v"},
+		{"a?.1", "1:4: expected a name, [ or ( after ?., got INT"},
+		{"a.[0]", "1:3: expected next token to be IDENT, got [ instead"},
+		{"a?.b = 1", "1:6: cannot assign to (a?.b)"},
+		{"a?.[0] = 1", "1:8: cannot assign to (a?.[0])"},
+		{"a ? b", "1:3: unexpected character '?'"},
+		{"[x for 1 in xs]", "1:8: expected next token to be IDENT, got INT instead"},
+		{"{k: v for k, v m}", "1:16: expected next token to be IN, got IDENT instead"},
+		{"[...a for a in xs]", "1:2: cannot spread the element of a comprehension"},
+		{"f(x: 1, 2)", "1:9: positional argument follows keyword argument"},
+		{"f(x: 1, x: 2)", "1:9: duplicate keyword argument x"},
+		{"let [a, (b)] = xs;", "1:9: expected a pattern, got ("},
+		{"macro([x]) { x }", "1:7: expected next token to be IDENT, got [ instead"},
+		{"let x 5;", "1:7: expected next token to be =, got INT instead"},
+		{"1 +\n  ;", "2:3: no prefix parse function for ; found"},
+		{"let x = 0b102;", `1:9: invalid integer literal "0b102": invalid digit '2' in binary literal`},
+		{"1 + 0xG;", `1:5: invalid integer literal "0xG": invalid digit 'G' in hexadecimal literal`},
+		{"0o;", `1:1: invalid integer literal "0o": octal literal has no digits`},
+		{"1__000;", `1:1: invalid integer literal "1__000": '_' must separate successive digits`},
+		{"1_;", `1:1: invalid integer literal "1_": '_' must separate successive digits`},
+		{"1_.5;", `1:1: invalid float literal "1_.5"`},
+		{"let s = \"abc;", "1:9: unterminated string literal"},
+		{"let s = \"a${}b\";", "1:11: empty interpolation"},
+		{"let s = \"a${1 + }b\";", "1:11: incomplete expression in interpolation"},
+		{"let s = \"a${x y}\";", "1:11: incomplete expression in interpolation"},
+		{"\"${\"${(}\"}\"", "1:5: incomplete expression in interpolation"},
+		{"let x = 1 @ 2;", "1:11: unexpected character '@'"},
+		{"/* 1 + 2;", "1:1: unterminated block comment"},
+		{"break;", "1:1: break outside loop or switch"},
+		{"switch (x) { default: 1 default: 2 }", "1:25: multiple defaults in switch"},
+		{"switch (x) { case 1: fallthrough; x case 2: }", "1:35: fallthrough must be the last statement of a case"},
+		{"switch (x) { case 1: fallthrough }", "1:14: cannot fallthrough the last case of a switch"},
+		{"if (x) { fallthrough }", "1:10: fallthrough statement out of place"},
+		{"switch (x) { x }", "1:14: expected case or default, got IDENT"},
+		{"while (true) { switch (x) { case 1: fn() { break; } } }", "1:44: break outside loop or switch"},
+		{"for (i in xs) { push(r, [if (i == 1) { continue; } else { i }]); }", "1:40: continue used as a value"},
+		{"for (i in xs) { let x = if (i == 2) { break; } else { i }; }", "1:39: break used as a value"},
+		{"while (x) { f(if (x) { 1 } else if (y) { break }) }", "1:42: break used as a value"},
+		{"while (x) { let y = if (x) { switch (x) { default: continue } } }", "1:52: continue used as a value"},
+		{"1 = 2;", "1:3: cannot assign to 1"},
+		{"match (x) { [...a, b] => 1 }", "1:20: rest pattern must be the last element"},
+		{"match (x) { x + 1 => 1 }", "1:15: expected next token to be =>, got + instead"},
+		{"match (x) { (x) => 1 }", "1:13: expected a pattern, got ("},
+		{"match (x) { 1 => 1 2 => 2 }", "1:20: expected next token to be ,, got INT instead"},
+		{"f(x) += 2;", "1:6: cannot assign to f(x)"},
+		{"while (true) { fn() { continue; } }", "1:23: continue outside loop"},
+		{"for (x in xs { x }", "1:14: expected next token to be ), got { instead"},
+		{"for (let i = 0 i) { }", "1:16: expected next token to be ;, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+		expectedAST    string
+	}{
+		{
+			"let x = add(1, 2; let y = 3;",
+			[]string{"1:17: expected next token to be ), got ; instead"},
+			"let x = ;let y = 3;",
+		},
+		{
+			"if (x { 1 } let y = 2; y",
+			[]string{"1:7: expected next token to be ), got { instead"},
+			"let y = 2;y",
+		},
+		{
+			"let = 5; let z = 1;",
+			[]string{"1:5: expected a pattern, got ="},
+			"let z = 1;",
+		},
+		{
+			"let f = fn(x) { let = 1; x }; f(1)",
+			[]string{"1:21: expected a pattern, got ="},
+			"let f = fn(x) x;f(1)",
+		},
+		{
+			"let x = 1 @ 2; let y = 2;",
+			[]string{"1:11: unexpected character '@'"},
+			"let x = 1;let y = 2;",
+		},
+		{
+			"let a = (1 + ; let b = [1, 2; let c = 3;",
+			[]string{
+				"1:14: no prefix parse function for ; found",
+				"1:29: expected next token to be ], got ; instead",
+			},
+			"let a = ;let b = ;let c = 3;",
+		},
+		{
+			"switch (x) { case 1: let = 1; case 2: y } let z = 1;",
+			[]string{"1:26: expected a pattern, got ="},
+			"switch (x) { case 1: case 2: y }let z = 1;",
+		},
+		{
+			"let f = fn(x) { x + }; let y = 2; let z = ;",
+			[]string{
+				"1:21: no prefix parse function for } found",
+				"1:43: no prefix parse function for ; found",
+			},
+			"let f = fn(x) ;let y = 2;let z = ;",
+		},
+		{
+			"let x = 1 + ; let y = 2;",
+			[]string{"1:13: no prefix parse function for ; found"},
+			"let x = ;let y = 2;",
+		},
+		{
+			"let ps = [[x, y] for x in a for y in b]; let z = 1;",
+			[]string{"1:29: expected next token to be ], got FOR instead"},
+			"let ps = ;let z = 1;",
+		},
+		{
+			"let ys = [x for x in xs if a if b]; let z = 1;",
+			[]string{"1:30: expected next token to be ], got IF instead"},
+			"let ys = ;let z = 1;",
+		},
+		{
+			"let ys = [...a for a in [[1], [2]]]; let z = 1;",
+			[]string{"1:11: cannot spread the element of a comprehension"},
+			"let ys = ;let z = 1;",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q. expected=%q, got=%q",
+				tt.input, tt.expectedErrors, errors)
+			continue
+		}
+
+		for i, expected := range tt.expectedErrors {
+			if errors[i] != expected {
+				t.Errorf("errors[%d] wrong for %q. expected=%q, got=%q",
+					i, tt.input, expected, errors[i])
+			}
+		}
+
+		if program.String() != tt.expectedAST {
+			t.Errorf("wrong partial AST for %q. expected=%q, got=%q",
+				tt.input, tt.expectedAST, program.String())
+		}
+	}
+}
+
+func TestErrorRecoveryKeepsClosingBrace(t *testing.T) {
+	input := "let f = fn(x) { x + }; let y = 2; let z = ;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d",
+			len(program.Statements))
+	}
+
+	for i, name := range []string{"f", "y", "z"} {
+		if !testLetStatement(t, program.Statements[i], name) {
+			return
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	input := "let x = f(1, 2;\nlet y = );"
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) != 2 {
+		t.Fatalf("wrong number of diagnostics. expected=2, got=%d (%v)",
+			len(diagnostics), diagnostics)
+	}
+
+	missing := diagnostics[0]
+	if missing.Expected != token.RPAREN || missing.Found != token.SEMICOLON {
+		t.Errorf("wrong expected/found. got=%q/%q", missing.Expected, missing.Found)
+	}
+	if missing.Pos.String() != "1:15" || missing.End.String() != "1:16" {
+		t.Errorf("wrong span. got=%s-%s", missing.Pos, missing.End)
+	}
+
+	unexpected := diagnostics[1]
+	if unexpected.Expected != "" || unexpected.Found != token.RPAREN {
+		t.Errorf("wrong expected/found. got=%q/%q", unexpected.Expected, unexpected.Found)
+	}
+	if unexpected.Pos.String() != "2:9" || unexpected.End.Offset != unexpected.Pos.Offset+1 {
+		t.Errorf("wrong span. got=%s-%s", unexpected.Pos, unexpected.End)
+	}
+}
+
+func TestInterpolatedStringParsing(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedParts int
+		expected      string
+	}{
+		{`"total: ${price * qty}"`, 2, `"total: ${(price * qty)}"`},
+		{`"${a}${b}"`, 2, `"${a}${b}"`},
+		{`"<${f(x)}> and \${literal}"`, 3, `"<${f(x)}> and \${literal}"`},
+		{`"outer ${"inner ${x}"}!"`, 3, `"outer ${"inner ${x}"}!"`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		str, ok := stmt.Expression.(*ast.InterpolatedString)
+		if !ok {
+			t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+		}
+
+		if len(str.Parts) != tt.expectedParts {
+			t.Errorf("wrong number of parts. want=%d, got=%d", tt.expectedParts, len(str.Parts))
+		}
+
+		if str.String() != tt.expected {
+			t.Errorf("str.String() wrong. want=%q, got=%q", tt.expected, str.String())
+		}
+	}
+}
+
+func TestLexerDiagnosticSpans(t *testing.T) {
+	tests := []string{
+		`let x = 1 @ 2;`,
+		`let s = "a\qb";`,
+		`let s = "a\u{zz}b";`,
+		`let s = "a${}b";`,
+		`let s = "abc`,
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) == 0 {
+			t.Errorf("no diagnostics for %q", input)
+			continue
+		}
+		d := diagnostics[0]
+		if d.End.Offset <= d.Pos.Offset {
+			t.Errorf("empty span for %q (%s). got=%s-%s", input, d.Message, d.Pos, d.End)
+		}
+	}
+}
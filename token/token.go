@@ -1,12 +1,63 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
+// Position describes a location in the source. Line and Column are
+// 1-based; Offset is the 0-based index into the input.
+type Position struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+}
+
+// IsValid reports whether the position was set by the lexer.
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // first character of the token
+	End     Position // just past the last character of the token
+
+	// Leading holds the whitespace and comments before the token. It is
+	// only filled in by a lexer created with lexer.NewWithTrivia.
+	Leading []Trivia
+}
+
+// Trivia is source text between tokens that the parser ignores. Joining
+// the trivia and the source text of every token up to EOF gives back the
+// original input.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+	Pos  Position
 }
 
+type TriviaKind string
+
+const (
+	WHITESPACE    TriviaKind = "WHITESPACE"    // spaces, tabs and newlines
+	LINE_COMMENT  TriviaKind = "LINE_COMMENT"  // "// ..." without the newline
+	BLOCK_COMMENT TriviaKind = "BLOCK_COMMENT" // "/* ... */"
+)
+
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
@@ -14,8 +65,16 @@ const (
 	// Identifiers + literals
 	IDENT  = "IDENT"
 	INT    = "INT"
+	FLOAT  = "FLOAT"
 	STRING = "STRING"
 
+	// INTERPOLATION is the text of a string literal up to an embedded
+	// "${". The expression's tokens follow, and the literal continues
+	// with an INTERPOLATION_END, the "}" that closes the "${", and the
+	// literal continues with another INTERPOLATION or ends with a STRING.
+	INTERPOLATION     = "INTERPOLATION"
+	INTERPOLATION_END = "INTERPOLATION_END"
+
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -23,14 +82,43 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
+	POWER    = "**"
 
-	LT = "<"
-	GT = ">"
+	LT    = "<"
+	GT    = ">"
+	LT_EQ = "<="
+	GT_EQ = ">="
+
+	BIT_AND     = "&"
+	BIT_OR      = "|"
+	BIT_XOR     = "^"
+	SHIFT_LEFT  = "<<"
+	SHIFT_RIGHT = ">>"
 
 	EQ     = "=="
 	NOT_EQ = "!="
 
+	AND     = "&&"
+	OR      = "||"
+	NULLISH = "??"
+
+	OPTIONAL_CHAIN = "?."
+
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+
+	ARROW = "=>"
+	PIPE  = "|>"
+
+	RANGE           = ".."
+	RANGE_INCLUSIVE = "..="
+
 	// Delimiters
+	DOT       = "."
+	ELLIPSIS  = "..."
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
@@ -50,19 +138,42 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+
+	MATCH = "MATCH"
+
+	SWITCH      = "SWITCH"
+	FALLTHROUGH = "FALLTHROUGH"
+
+	// CASE and DEFAULT are only keywords at the start of a switch clause,
+	// so the lexer returns them as IDENT and the parser retypes them.
+	CASE    = "CASE"
+	DEFAULT = "DEFAULT"
 
 	MACRO = "MACRO"
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"macro":  MACRO,
+	"fn":          FUNCTION,
+	"let":         LET,
+	"true":        TRUE,
+	"false":       FALSE,
+	"if":          IF,
+	"else":        ELSE,
+	"return":      RETURN,
+	"while":       WHILE,
+	"for":         FOR,
+	"in":          IN,
+	"break":       BREAK,
+	"continue":    CONTINUE,
+	"match":       MATCH,
+	"switch":      SWITCH,
+	"fallthrough": FALLTHROUGH,
+	"macro":       MACRO,
 }
 
 func LookupIdent(ident string) TokenType {